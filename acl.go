@@ -0,0 +1,113 @@
+package schemaspy
+
+import (
+	"strings"
+)
+
+// Privilege is a single granted privilege, from an access control list.
+type Privilege struct {
+	// Grantee is a role name, or "public" for PUBLIC
+	Grantee string `json:"grantee"`
+	// Privilege is "SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE",
+	// "REFERENCES", "TRIGGER", "EXECUTE", "USAGE", "CREATE", "CONNECT",
+	// "TEMPORARY", "SET", "ALTER SYSTEM", or "MAINTAIN"
+	Privilege string `json:"privilege"`
+	// Grantable is true WITH GRANT OPTION
+	Grantable bool   `json:"grantable"`
+	Grantor   string `json:"grantor"`
+}
+
+// Granted is true if the privilege is granted to the role, either directly,
+// or via PUBLIC. Membership of other roles is not considered.
+func Granted(ps []Privilege, role, privilege string) bool {
+	for _, p := range ps {
+		if p.Privilege == privilege && (p.Grantee == role || p.Grantee == "public") {
+			return true
+		}
+	}
+	return false
+}
+
+// aclitem privilege letters
+// https://www.postgresql.org/docs/current/ddl-priv.html#PRIVILEGE-ABBREVS-TABLE
+var aclPrivileges = map[byte]string{
+	'r': "SELECT",
+	'w': "UPDATE",
+	'a': "INSERT",
+	'd': "DELETE",
+	'D': "TRUNCATE",
+	'x': "REFERENCES",
+	't': "TRIGGER",
+	'X': "EXECUTE",
+	'U': "USAGE",
+	'C': "CREATE",
+	'c': "CONNECT",
+	'T': "TEMPORARY",
+	's': "SET",
+	'A': "ALTER SYSTEM",
+	'm': "MAINTAIN",
+}
+
+// parseACL parses aclitems in their text form, such as
+// "bob=arw*/alice". Unparsable items are skipped.
+func parseACL(acl []string) []Privilege {
+	var res []Privilege
+	for _, item := range acl {
+		grantee, rest := aclRole(item)
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = rest[1:]
+		slash := strings.Index(rest, "/")
+		if slash < 0 {
+			continue
+		}
+		privs := rest[:slash]
+		grantor, _ := aclRole(rest[slash+1:])
+		if grantee == "" {
+			grantee = "public"
+		}
+		for i := 0; i < len(privs); i++ {
+			name, ok := aclPrivileges[privs[i]]
+			if !ok {
+				continue
+			}
+			p := Privilege{
+				Grantee:   grantee,
+				Privilege: name,
+				Grantor:   grantor,
+			}
+			if i+1 < len(privs) && privs[i+1] == '*' {
+				p.Grantable = true
+				i++
+			}
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// aclRole reads a role name at the start of an aclitem, which can be double
+// quoted. It returns the name and the remainder.
+func aclRole(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, "=/")
+		if end < 0 {
+			return s, ""
+		}
+		return s[:end], s[end:]
+	}
+	var name strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] == '"' {
+			if i+1 < len(s) && s[i+1] == '"' {
+				name.WriteByte('"')
+				i++
+				continue
+			}
+			return name.String(), s[i+1:]
+		}
+		name.WriteByte(s[i])
+	}
+	return name.String(), ""
+}
@@ -0,0 +1,59 @@
+package schemaspy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseACL(t *testing.T) {
+	for _, c := range []struct {
+		acl  []string
+		want []Privilege
+	}{
+		{
+			acl:  nil,
+			want: nil,
+		},
+		{
+			acl: []string{"=r/alice"},
+			want: []Privilege{
+				{Grantee: "public", Privilege: "SELECT", Grantor: "alice"},
+			},
+		},
+		{
+			acl: []string{"bob=ar*/alice", "alice=X/alice"},
+			want: []Privilege{
+				{Grantee: "bob", Privilege: "INSERT", Grantor: "alice"},
+				{Grantee: "bob", Privilege: "SELECT", Grantable: true, Grantor: "alice"},
+				{Grantee: "alice", Privilege: "EXECUTE", Grantor: "alice"},
+			},
+		},
+		{
+			acl: []string{`"my ""app"" role"=U/"the=owner"`},
+			want: []Privilege{
+				{Grantee: `my "app" role`, Privilege: "USAGE", Grantor: "the=owner"},
+			},
+		},
+		{
+			acl:  []string{"broken"},
+			want: nil,
+		},
+	} {
+		if have, want := parseACL(c.acl), c.want; !reflect.DeepEqual(have, want) {
+			t.Errorf("%q: have %#v, want %#v", c.acl, have, want)
+		}
+	}
+}
+
+func TestGranted(t *testing.T) {
+	ps := parseACL([]string{"=r/alice", "bob=w/alice"})
+	if have, want := Granted(ps, "bob", "SELECT"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := Granted(ps, "bob", "UPDATE"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := Granted(ps, "carol", "UPDATE"), false; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
@@ -0,0 +1,80 @@
+package schemaspy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Database has multiple schemas, all read in a single transaction.
+type Database struct {
+	// Schemas by name
+	Schemas map[string]*Schema
+}
+
+// SchemaNames gives the names of all schemas, ordered alphabetically.
+func (d *Database) SchemaNames() []string {
+	var names []string
+	for n := range d.Schemas {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DescribeDatabase describes the given schemas. Leave schemas empty for all
+// schemas, except for the system schemas ("pg_catalog", "information_schema",
+// &c.). The shared catalogs are only read once.
+//
+// q should be a "repeatable read" or "serializable" transaction, so all
+// schemas are from the same snapshot. See Describe() for the errors.
+func DescribeDatabase(ctx context.Context, q Queryer, schemas ...string) (*Database, error) {
+	d, err := describeDatabase(ctx, q, schemas)
+	if err != nil {
+		return nil, asTimeout(ctx, err)
+	}
+	return d, nil
+}
+
+func describeDatabase(ctx context.Context, q Queryer, schemas []string) (*Database, error) {
+	dbs, err := pgNamespace(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		for name := range dbs {
+			if isSystemSchema(name) {
+				continue
+			}
+			schemas = append(schemas, name)
+		}
+	}
+
+	catalog, err := loadCatalog(ctx, q, dbs)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Database{
+		Schemas: map[string]*Schema{},
+	}
+	for _, schema := range schemas {
+		db, ok := dbs[schema]
+		if !ok {
+			return nil, fmt.Errorf("schema %q not found in pg_catalog", schema)
+		}
+		oids, err := catalog.loadNamespace(ctx, q, db.OID)
+		if err != nil {
+			return nil, err
+		}
+		d.Schemas[schema] = describe(oids, db)
+	}
+	return d, nil
+}
+
+// pg_catalog, pg_toast, pg_temp_1, and friends. Names starting with "pg_" are
+// reserved.
+func isSystemSchema(name string) bool {
+	return name == "information_schema" || strings.HasPrefix(name, "pg_")
+}
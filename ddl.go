@@ -0,0 +1,39 @@
+package schemaspy
+
+import (
+	"io"
+	"strings"
+)
+
+// DDL gives the SQL to create the schema, and everything in it. See
+// WriteDDL().
+func (s *Schema) DDL() string {
+	var b strings.Builder
+	s.WriteDDL(&b)
+	return b.String()
+}
+
+// WriteDDL writes the CREATE statements for the schema and all its objects,
+// in dependency order: types, sequences, functions, tables, views, indexes,
+// constraints, triggers, policies, event triggers, and comments. The output
+// only depends on the Schema, so it can be committed and compared.
+//
+// Owners and privileges are not included. It's the same SQL as
+// Migrate(nil, s), with a CREATE SCHEMA in front.
+func (s *Schema) WriteDDL(w io.Writer) error {
+	stmts := Migrate(nil, s)
+	if s.Name != "" {
+		stmts = append(Migration{{SQL: "CREATE SCHEMA " + quoteIdent(s.Name)}}, stmts...)
+	}
+	for i, st := range stmts {
+		sql := st.SQL + ";\n"
+		if i > 0 {
+			// a blank line between statements
+			sql = "\n" + sql
+		}
+		if _, err := io.WriteString(w, sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package schemaspy
+
+import (
+	"testing"
+)
+
+func TestDDL(t *testing.T) {
+	s := &Schema{
+		Name:    "Shop",
+		Comment: "all things shop",
+		Relations: map[string]Relation{
+			"items": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id":    {FullType: "integer", NotNull: true, Position: 1},
+					"price": {FullType: "numeric(10,2)", Position: 2},
+				},
+				Constraints: []string{"items_pkey"},
+			},
+			"cheap": {
+				Type:       "view",
+				Definition: " SELECT id\n   FROM \"Shop\".items\n  WHERE (price < (1)::numeric);",
+			},
+		},
+		Constraints: map[string]map[string]Constraint{
+			"items": {
+				"items_pkey": {Type: "primary key", Table: "items", Columns: []string{"id"}, Index: "items_pkey", Validated: true},
+			},
+		},
+		Indexes: map[string]Index{
+			"items_pkey": {Table: "items", Unique: true, Primary: true, Constraint: "items_pkey", Columns: []string{"id"}},
+		},
+		Functions: map[string]Function{
+			"double(int4)": {
+				Name:          "double",
+				Kind:          "function",
+				Language:      "sql",
+				ArgumentTypes: []string{"int4"},
+				Arguments:     []Argument{{Name: "i", Mode: "in", Type: "int4"}},
+				Result:        "integer",
+				Volatility:    "immutable",
+				Src:           "SELECT i * 2",
+			},
+		},
+	}
+	want := `CREATE SCHEMA "Shop";
+
+CREATE OR REPLACE FUNCTION "Shop".double(i int4)
+ RETURNS integer
+ LANGUAGE sql
+ IMMUTABLE
+AS $function$SELECT i * 2$function$;
+
+CREATE TABLE "Shop".items (
+    id integer NOT NULL,
+    price numeric(10,2)
+);
+
+CREATE VIEW "Shop".cheap AS
+SELECT id
+   FROM "Shop".items
+  WHERE (price < (1)::numeric);
+
+ALTER TABLE "Shop".items ADD CONSTRAINT items_pkey PRIMARY KEY (id);
+
+COMMENT ON SCHEMA "Shop" IS 'all things shop';
+`
+	if have := s.DDL(); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+
+	if have, want := (&Schema{}).DDL(), ""; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
@@ -0,0 +1,388 @@
+package schemaspy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is what happened to an object, see Change.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Object is the type of object a Change is about.
+type Object string
+
+const (
+	ObjectSchema       Object = "schema"
+	ObjectType         Object = "type"
+	ObjectSequence     Object = "sequence"
+	ObjectFunction     Object = "function"
+	ObjectRelation     Object = "relation"
+	ObjectColumn       Object = "column"
+	ObjectTrigger      Object = "trigger"
+	ObjectPolicy       Object = "policy"
+	ObjectIndex        Object = "index"
+	ObjectConstraint   Object = "constraint"
+	ObjectEventTrigger Object = "event trigger"
+)
+
+// Change is a single difference between two schemas.
+type Change struct {
+	Kind   ChangeKind
+	Object Object
+	// Table is the relation of a column, trigger, policy, index, or
+	// constraint. For indexes which moved to another table it's the new
+	// table.
+	Table string
+	// Name of the object. Functions are named by their signature, and the
+	// schema itself has an empty name.
+	Name string
+	// Field is the name of the changed struct field, such as "NotNull" or
+	// "FullType" for a column. Only set for Changed.
+	Field string
+	// Old and New are the values of Field for Changed. For Added New is the
+	// object (a Relation, Column, Index, &c.), and for Removed Old is.
+	Old interface{}
+	New interface{}
+}
+
+// String gives a single line description, such as:
+//
+//	changed column "users"."name" NotNull: false -> true
+func (c Change) String() string {
+	var name string
+	switch {
+	case c.Object == ObjectSchema:
+	case c.Table != "" && (c.Object == ObjectColumn || c.Object == ObjectTrigger || c.Object == ObjectPolicy || c.Object == ObjectConstraint):
+		name = fmt.Sprintf(" %q.%q", c.Table, c.Name)
+	default:
+		name = fmt.Sprintf(" %q", c.Name)
+	}
+	s := string(c.Kind) + " " + string(c.Object) + name
+	if c.Kind == Changed {
+		s += fmt.Sprintf(" %s: %s -> %s", c.Field, formatValue(c.Old), formatValue(c.New))
+	}
+	return s
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Changes is the result of Diff().
+type Changes []Change
+
+// Filter returns the changes for which keep returns true.
+func (cs Changes) Filter(keep func(Change) bool) Changes {
+	var res Changes
+	for _, c := range cs {
+		if keep(c) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Objects returns the changes about any of the given objects.
+func (cs Changes) Objects(objects ...Object) Changes {
+	return cs.Filter(func(c Change) bool {
+		for _, o := range objects {
+			if c.Object == o {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// String gives one line per change.
+func (cs Changes) String() string {
+	var b strings.Builder
+	for _, c := range cs {
+		b.WriteString(c.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Diff lists what needs to change to get from schema a to schema b. Either
+// can be nil, which is the same as an empty schema. The schema names are not
+// compared, so a and b can come from different schemas or databases.
+// References to objects in the schema itself are compared without the schema
+// name, both in QNames, such as Relation.Inherits, and in SQL, such as
+// Column.Default or Constraint.Definition.
+//
+// The order is stable: the schema itself, types, sequences, functions,
+// relations (each followed by its columns, triggers, and policies), indexes,
+// constraints, and event triggers. Within each group objects are ordered
+// by name, constraints by table and name, and fields in struct order.
+//
+// Columns of added or removed relations are not listed separately. Fields
+// which only describe the state of the database, such as Sequence.LastValue,
+// Column.AttNum, or Index.Valid, or which are derived from other fields, such
+// as Relation.Children or Column.Type, are not compared. That includes those
+// fields in nested values, such as the Columns in Type.Attributes.
+// Empty and nil slices and maps are equal.
+func Diff(a, b *Schema) Changes {
+	if a == nil {
+		a = &Schema{}
+	}
+	if b == nil {
+		b = &Schema{}
+	}
+	var (
+		cs Changes
+		d  = differ{newLocal(a.Name), newLocal(b.Name)}
+	)
+	cs = append(cs, d.fields(Change{Object: ObjectSchema}, *a, *b)...)
+
+	for _, n := range keys(a.Types, b.Types) {
+		cs = append(cs, d.object(Change{Object: ObjectType, Name: n}, a.Types, b.Types)...)
+	}
+	for _, n := range keys(a.Sequences, b.Sequences) {
+		cs = append(cs, d.object(Change{Object: ObjectSequence, Name: n}, a.Sequences, b.Sequences)...)
+	}
+	for _, n := range keys(a.Functions, b.Functions) {
+		cs = append(cs, d.object(Change{Object: ObjectFunction, Name: n}, a.Functions, b.Functions)...)
+	}
+	for _, n := range keys(a.Relations, b.Relations) {
+		cs = append(cs, d.object(Change{Object: ObjectRelation, Name: n}, a.Relations, b.Relations)...)
+		ra, oka := a.Relations[n]
+		rb, okb := b.Relations[n]
+		if oka && okb {
+			for _, c := range keys(ra.Columns, rb.Columns) {
+				cs = append(cs, d.object(Change{Object: ObjectColumn, Table: n, Name: c}, ra.Columns, rb.Columns)...)
+			}
+		}
+		for _, t := range keys(ra.Triggers, rb.Triggers) {
+			cs = append(cs, d.object(Change{Object: ObjectTrigger, Table: n, Name: t}, ra.Triggers, rb.Triggers)...)
+		}
+		for _, p := range keys(ra.Policies, rb.Policies) {
+			cs = append(cs, d.object(Change{Object: ObjectPolicy, Table: n, Name: p}, ra.Policies, rb.Policies)...)
+		}
+	}
+	for _, n := range keys(a.Indexes, b.Indexes) {
+		table := a.Indexes[n].Table
+		if i, ok := b.Indexes[n]; ok {
+			table = i.Table
+		}
+		cs = append(cs, d.object(Change{Object: ObjectIndex, Table: table, Name: n}, a.Indexes, b.Indexes)...)
+	}
+	for _, t := range keys(a.Constraints, b.Constraints) {
+		ca, cb := a.Constraints[t], b.Constraints[t]
+		for _, n := range keys(ca, cb) {
+			cs = append(cs, d.object(Change{Object: ObjectConstraint, Table: t, Name: n}, ca, cb)...)
+		}
+	}
+	for _, n := range keys(a.EventTriggers, b.EventTriggers) {
+		cs = append(cs, d.object(Change{Object: ObjectEventTrigger, Name: n}, a.EventTriggers, b.EventTriggers)...)
+	}
+	return cs
+}
+
+// Fields which are not compared, by struct.
+var diffIgnore = map[reflect.Type]map[string]bool{
+	reflect.TypeOf(Schema{}): set(
+		"Name", "Relations", "Tables", "Views", "Materialized", "Partitioned",
+		"Sequences", "Indexes", "Constraints", "Types", "EventTriggers",
+		"Functions",
+	),
+	reflect.TypeOf(Relation{}): set(
+		"Columns", "Children", "Indexes", "Constraints", "Populated",
+		"ConcurrentRefreshIndexes", "Triggers", "Policies", "Partitions",
+		"DefaultPartition", "DetachPending",
+	),
+	reflect.TypeOf(Column{}): set("Type", "TypeName", "Array", "Position", "AttNum"),
+	reflect.TypeOf(Index{}):  set("Columns", "Definition", "Valid", "Ready", "Live"),
+	// everything else is in the Definition
+	reflect.TypeOf(Constraint{}): set(
+		"Type", "Columns", "Index", "RefTable", "RefColumns", "OnUpdate",
+		"OnDelete", "Deferrable", "InitiallyDeferred", "Validated",
+	),
+	reflect.TypeOf(Type{}):     set("Multirange"),
+	reflect.TypeOf(Trigger{}):  set("Definition"),
+	reflect.TypeOf(Sequence{}): set("LastValue", "IsCalled"),
+	reflect.TypeOf(Function{}): set("Name", "ArgumentTypes", "Definition"),
+}
+
+func set(names ...string) map[string]bool {
+	m := map[string]bool{}
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+// differ has the two schemas which are compared.
+type differ struct {
+	a, b local
+}
+
+// object compares the entry c.Name of two maps.
+func (d differ) object(c Change, a, b interface{}) []Change {
+	key := reflect.ValueOf(c.Name)
+	va := reflect.ValueOf(a).MapIndex(key)
+	vb := reflect.ValueOf(b).MapIndex(key)
+	switch {
+	case !va.IsValid():
+		c.Kind = Added
+		c.New = vb.Interface()
+		return []Change{c}
+	case !vb.IsValid():
+		c.Kind = Removed
+		c.Old = va.Interface()
+		return []Change{c}
+	default:
+		return d.fields(c, va.Interface(), vb.Interface())
+	}
+}
+
+// fields compares all fields of two structs of the same type.
+func (d differ) fields(c Change, a, b interface{}) []Change {
+	var (
+		res    []Change
+		va     = reflect.ValueOf(a)
+		vb     = reflect.ValueOf(b)
+		ignore = diffIgnore[va.Type()]
+	)
+	for i := 0; i < va.NumField(); i++ {
+		f := va.Type().Field(i)
+		if ignore[f.Name] {
+			continue
+		}
+		fa, fb := va.Field(i), vb.Field(i)
+		if equalValue(d.a.value(fa), d.b.value(fb)) {
+			continue
+		}
+		c := c
+		c.Kind = Changed
+		c.Field = f.Name
+		c.Old = fa.Interface()
+		c.New = fb.Interface()
+		res = append(res, c)
+	}
+	return res
+}
+
+func equalValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// local removes the schema name from references to objects in the schema.
+type local struct {
+	schema string
+	// qualifier matches the schema name in front of a name in SQL, such as
+	// in "app.users"
+	qualifier *regexp.Regexp
+}
+
+func newLocal(schema string) local {
+	l := local{schema: schema}
+	if schema != "" {
+		names := regexp.QuoteMeta(quoteIdent(schema))
+		if q := `"` + strings.Replace(schema, `"`, `""`, -1) + `"`; q != quoteIdent(schema) {
+			names += "|" + regexp.QuoteMeta(q)
+		}
+		l.qualifier = regexp.MustCompile(`(^|[^a-zA-Z0-9_$."])(?:` + names + `)\.`)
+	}
+	return l
+}
+
+// value gives a copy of v without the schema name in QNames and in SQL, and
+// without the fields from diffIgnore in nested structs.
+func (l local) value(v reflect.Value) reflect.Value {
+	return l.walk(v, "", true)
+}
+
+// rename gives a copy of v with references to objects in the schema moved
+// to another schema.
+func (l local) rename(v reflect.Value, schema string) reflect.Value {
+	return l.walk(v, schema, false)
+}
+
+func (l local) walk(v reflect.Value, schema string, ignore bool) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		if l.qualifier == nil {
+			return v
+		}
+		prefix := ""
+		if schema != "" {
+			prefix = strings.Replace(quoteIdent(schema), "$", "$$", -1) + "."
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.SetString(l.qualifier.ReplaceAllString(v.String(), "${1}"+prefix))
+		return res
+	case reflect.Struct:
+		if q, ok := v.Interface().(QName); ok {
+			if q.Schema == l.schema {
+				q.Schema = schema
+			}
+			return reflect.ValueOf(q)
+		}
+		var skip map[string]bool
+		if ignore {
+			skip = diffIgnore[v.Type()]
+		}
+		res := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if !skip[v.Type().Field(i).Name] {
+				res.Field(i).Set(l.walk(v.Field(i), schema, ignore))
+			}
+		}
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(l.walk(v.Index(i), schema, ignore))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			res.SetMapIndex(k, l.walk(v.MapIndex(k), schema, ignore))
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// keys gives the sorted union of the keys of two map[string]... values.
+func keys(a, b interface{}) []string {
+	seen := map[string]bool{}
+	for _, m := range []interface{}{a, b} {
+		for _, k := range reflect.ValueOf(m).MapKeys() {
+			seen[k.String()] = true
+		}
+	}
+	var res []string
+	for k := range seen {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
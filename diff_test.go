@@ -0,0 +1,173 @@
+package schemaspy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := &Schema{
+		Name:  "live",
+		Owner: "alice",
+		Relations: map[string]Relation{
+			"users": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id":   {Type: "int4", FullType: "integer", NotNull: true, Position: 1, AttNum: 1},
+					"name": {Type: "text", FullType: "text", Position: 2, AttNum: 2},
+					"old":  {Type: "text", FullType: "text", Position: 3, AttNum: 3},
+				},
+				Indexes: []string{"users_pkey"},
+			},
+			"gone": {Type: "table"},
+		},
+		Sequences: map[string]Sequence{
+			"users_id_seq": {Type: "integer", IncrementBy: 1, Cache: 1, LastValue: 12, IsCalled: true},
+		},
+		Indexes: map[string]Index{
+			"users_pkey": {Table: "users", Type: "btree", Unique: true, Primary: true, Columns: []string{"id"}},
+		},
+		Functions: map[string]Function{
+			"hello()": {Name: "hello", Src: "select 1"},
+		},
+	}
+	b := &Schema{
+		Name:  "wanted",
+		Owner: "alice",
+		Relations: map[string]Relation{
+			"users": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id":   {Type: "int8", FullType: "bigint", NotNull: true, Position: 1, AttNum: 1},
+					"name": {Type: "text", FullType: "text", NotNull: true, Position: 2, AttNum: 4},
+				},
+				Indexes: []string{"users_name", "users_pkey"},
+			},
+			"new": {Type: "view", Definition: " SELECT 1;"},
+		},
+		Sequences: map[string]Sequence{
+			"users_id_seq": {Type: "bigint", IncrementBy: 1, Cache: 1},
+		},
+		Indexes: map[string]Index{
+			"users_pkey": {Table: "users", Type: "btree", Unique: true, Primary: true, Columns: []string{"id"}},
+			"users_name": {Table: "users", Type: "btree", Columns: []string{"name"}},
+		},
+		Functions: map[string]Function{
+			"hello()": {Name: "hello", Src: "select 2"},
+		},
+	}
+
+	cs := Diff(a, b)
+	var have []string
+	for _, c := range cs {
+		have = append(have, c.String())
+	}
+	want := []string{
+		`changed sequence "users_id_seq" Type: "integer" -> "bigint"`,
+		`changed function "hello()" Src: "select 1" -> "select 2"`,
+		`removed relation "gone"`,
+		`added relation "new"`,
+		`changed column "users"."id" FullType: "integer" -> "bigint"`,
+		`changed column "users"."name" NotNull: false -> true`,
+		`removed column "users"."old"`,
+		`added index "users_name"`,
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := cs[7], (Change{
+		Kind:   Added,
+		Object: ObjectIndex,
+		Table:  "users",
+		Name:   "users_name",
+		New:    b.Indexes["users_name"],
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := len(cs.Objects(ObjectColumn, ObjectIndex)), 4; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := len(cs.Filter(func(c Change) bool { return c.Kind == Removed })), 2; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := len(Diff(a, a)), 0; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := len(Diff(nil, b)), 7; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestDiffEmpty(t *testing.T) {
+	a := &Schema{Relations: map[string]Relation{"t": {Options: nil}}}
+	b := &Schema{Relations: map[string]Relation{"t": {Options: []string{}}}}
+	if have, want := len(Diff(a, b)), 0; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestDiffSchemaNames(t *testing.T) {
+	ddl := func(schema string) *Schema {
+		t.Helper()
+		s, err := ParseDDL(schema, `
+CREATE SCHEMA `+schema+`;
+SET search_path TO `+schema+`;
+CREATE TYPE pair AS (a int, b int);
+CREATE FUNCTION first(p pair) RETURNS int AS $$ SELECT p.a $$ LANGUAGE SQL;
+CREATE TABLE parent (id int);
+CREATE TABLE child (extra int) INHERITS (parent);
+CREATE TABLE events (at date) PARTITION BY RANGE (at);
+CREATE TABLE events_2020 PARTITION OF events FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');
+CREATE INDEX ON events (at);
+CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE TABLE users (id serial PRIMARY KEY, m mood);
+CREATE TABLE posts (user_id int REFERENCES users (id));
+CREATE VIEW moods AS SELECT m FROM `+schema+`.users;
+CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE TRIGGER touch BEFORE INSERT ON users FOR EACH ROW EXECUTE FUNCTION touch();
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	live, wanted := ddl("live"), ddl("wanted")
+	if have, want := wanted.Relations["child"].Inherits, []QName{{"wanted", "parent"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := wanted.Relations["users"].Columns["m"].FullType, "wanted.mood"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// attribute numbers change when a column is dropped
+	pair := wanted.Types["pair"]
+	pair.Attributes = map[string]Column{}
+	for n, c := range wanted.Types["pair"].Attributes {
+		c.Position++
+		c.AttNum += 2
+		pair.Attributes[n] = c
+	}
+	wanted.Types["pair"] = pair
+
+	if cs := Diff(live, wanted); len(cs) != 0 {
+		t.Errorf("changes:\n%s", cs)
+	}
+
+	wanted.Relations["child"] = Relation{
+		Type:     "table",
+		Columns:  wanted.Relations["child"].Columns,
+		Inherits: []QName{{"wanted", "parent"}, {"wanted", "events"}},
+	}
+	var have []string
+	for _, st := range Migrate(live, wanted) {
+		have = append(have, st.SQL)
+	}
+	if want := []string{
+		"ALTER TABLE live.child INHERIT live.events",
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
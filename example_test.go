@@ -1,15 +1,16 @@
 package schemaspy_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 
-	"github.com/alicebob/schemaspy"
+	"github.com/alicebob/schemaspy/pgxv5"
 )
 
 func Example() {
 	pgURL := "postgres://localhost"
-	schema, err := schemaspy.Public(pgURL)
+	schema, err := pgxv5.Public(context.Background(), pgURL)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -3,19 +3,31 @@
 package schemaspy
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgx"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/lib/pq"
 )
 
 const (
-	intPGURL = "postgres://@localhost/schemaspy"
+	intPGURL = "postgres://@localhost/schemaspy?sslmode=disable"
 )
 
+// intDriver is the database/sql driver, "pgx" or "postgres" (lib/pq). The
+// drivers scan catalog columns, such as oids, into different Go types.
+var intDriver = flag.String("driver", "pgx", "database/sql driver for the int tests")
+
 func setup(t *testing.T) *Schema {
-	db := mustDBPool(t)
-	d, err := Describe(db, "schemaspyint")
+	db := mustDB(t)
+	d, err := DescribeContext(context.Background(), db, "schemaspyint")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -29,7 +41,7 @@ func Test(t *testing.T) {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 
-	if have, want := len(d.Tables), 4; have != want {
+	if have, want := len(d.Tables), 16; have != want {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 }
@@ -52,16 +64,130 @@ func TestSimple(t *testing.T) {
 	}
 	if have, want := tab.Columns["id"], (Column{
 		Type:     "uuid",
+		TypeName: QName{"pg_catalog", "uuid"},
+		FullType: "uuid",
 		NotNull:  true,
 		Position: 1,
-	}); have != want {
+		AttNum:   1,
+	}); !reflect.DeepEqual(have, want) {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 	if have, want := tab.Columns["name"], (Column{
 		Type:     "text",
+		TypeName: QName{"pg_catalog", "text"},
+		FullType: "text",
 		NotNull:  false,
 		Position: 2,
-	}); have != want {
+		AttNum:   2,
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestColumnDefaults(t *testing.T) {
+	d := setup(t)
+
+	tab := d.Relations["defaulted"]
+	if have, want := tab.Columns["id"], (Column{
+		Type:     "int4",
+		TypeName: QName{"pg_catalog", "int4"},
+		FullType: "integer",
+		NotNull:  true,
+		Position: 1,
+		AttNum:   1,
+		Identity: "always",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["serial_id"], (Column{
+		Type:     "int4",
+		TypeName: QName{"pg_catalog", "int4"},
+		FullType: "integer",
+		NotNull:  true,
+		Position: 2,
+		AttNum:   2,
+		Default:  "nextval('schemaspyint.defaulted_serial_id_seq'::regclass)",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["created"], (Column{
+		Type:     "timestamptz",
+		TypeName: QName{"pg_catalog", "timestamptz"},
+		FullType: "timestamp with time zone",
+		NotNull:  true,
+		Position: 3,
+		AttNum:   3,
+		Default:  "now()",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["doubled"], (Column{
+		Type:      "int4",
+		TypeName:  QName{"pg_catalog", "int4"},
+		FullType:  "integer",
+		Position:  5,
+		AttNum:    5,
+		Generated: "(amount * 2)",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	d := setup(t)
+
+	tab := d.Relations["typed"]
+	for col, want := range map[string][2]string{
+		"code":  {"varchar", "character varying(255)"},
+		"price": {"numeric", "numeric(10,2)"},
+		"at":    {"timestamp", "timestamp(3) without time zone"},
+		"tags":  {"text[]", "text[]"},
+		"spot":  {"point", "point"},
+		"label": {"name", "name"},
+	} {
+		c := tab.Columns[col]
+		if have, want := c.Type, want[0]; have != want {
+			t.Errorf("%s: have %#v, want %#v", col, have, want)
+		}
+		if have, want := c.FullType, want[1]; have != want {
+			t.Errorf("%s: have %#v, want %#v", col, have, want)
+		}
+	}
+	if have, want := tab.Columns["tags"].TypeName, (QName{"pg_catalog", "text"}); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["tags"].Array, true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	// point and name have a typelem, but aren't arrays
+	for _, col := range []string{"spot", "label"} {
+		c := tab.Columns[col]
+		if have, want := c.TypeName, (QName{"pg_catalog", col}); have != want {
+			t.Errorf("%s: have %#v, want %#v", col, have, want)
+		}
+		if have, want := c.Array, false; have != want {
+			t.Errorf("%s: have %#v, want %#v", col, have, want)
+		}
+	}
+}
+
+func TestDropped(t *testing.T) {
+	d := setup(t)
+
+	tab := d.Relations["dropped"]
+	if have, want := tab.ColumnNames(), []string{"a", "c"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["c"], (Column{
+		Type:     "int4",
+		TypeName: QName{"pg_catalog", "int4"},
+		FullType: "integer",
+		Position: 2,
+		AttNum:   3,
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Indexes["dropped_c"].Columns, []string{"c"}; !reflect.DeepEqual(have, want) {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 }
@@ -71,16 +197,110 @@ func TestInherit(t *testing.T) {
 
 	{
 		tab := d.Relations["root"]
-		if have, want := tab.Inherits, []string(nil); !reflect.DeepEqual(have, want) {
+		if have, want := tab.Inherits, []QName(nil); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
-		if have, want := tab.Children, []string{"root_123"}; !reflect.DeepEqual(have, want) {
+		if have, want := tab.Children, []QName{
+			{"schemaspyint", "root_123"},
+			{"schemaspyint", "root_456"},
+			{"schemaspyint_other", "elsewhere"},
+		}; !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 	{
 		tab := d.Relations["root_123"]
-		if have, want := tab.Inherits, []string{"root"}; !reflect.DeepEqual(have, want) {
+		if have, want := tab.Inherits, []QName{{"schemaspyint", "root"}}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		// in INHERITS order
+		tab := d.Relations["root_456"]
+		if have, want := tab.Inherits, []QName{{"schemaspyint", "stamped"}, {"schemaspyint", "root"}}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	d := setup(t)
+
+	if have, want := d.Partitioned, []string{"measurement", "measurement_2021"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	{
+		tab := d.Relations["measurement"]
+		if have, want := tab.Type, "partitioned table"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.PartitionStrategy, "range"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.PartitionKey, []string{"logdate"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.Partitions, []QName{
+			{"schemaspyint", "measurement_2020"},
+			{"schemaspyint", "measurement_2021"},
+			{"schemaspyint", "measurement_rest"},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.DefaultPartition, (QName{"schemaspyint", "measurement_rest"}); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.Children, []QName(nil); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		tab := d.Relations["measurement_2020"]
+		if have, want := tab.Type, "table"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.PartitionOf, (QName{"schemaspyint", "measurement"}); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.PartitionBound, "FOR VALUES FROM ('2020-01-01') TO ('2021-01-01')"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.Inherits, []QName(nil); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		tab := d.Relations["measurement_2021"]
+		if have, want := tab.PartitionStrategy, "list"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := tab.PartitionKey, []string{"city"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	if have, want := d.Relations["measurement_rest"].PartitionBound, "DEFAULT"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := d.PartitionTree("measurement"), []QName{
+		{"schemaspyint", "measurement_2020"},
+		{"schemaspyint", "measurement_2021"},
+		{"schemaspyint", "measurement_2021_ams"},
+		{"schemaspyint", "measurement_rest"},
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	{
+		u := d.Indexes["measurement_logdate"]
+		if have, want := u.Partitioned, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		u = d.Indexes["measurement_2020_logdate_idx"]
+		if have, want := u.Partitioned, false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.PartitionOf, (QName{"schemaspyint", "measurement_logdate"}); have != want {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
@@ -88,7 +308,7 @@ func TestInherit(t *testing.T) {
 
 func TestIndexes(t *testing.T) {
 	d := setup(t)
-	if have, want := len(d.Indexes), 4; have != want {
+	if have, want := len(d.Indexes), 17; have != want {
 		t.Fatalf("have %#v, want %#v", have, want)
 	}
 	{
@@ -101,6 +321,14 @@ func TestIndexes(t *testing.T) {
 			Type:    "btree",
 			Unique:  true,
 			Columns: []string{"name"},
+			Keys: []IndexKey{
+				{Column: "name"},
+			},
+			Definition: "CREATE UNIQUE INDEX unique_indexed ON schemaspyint.indexed USING btree (name)",
+			Immediate:  true,
+			Valid:      true,
+			Ready:      true,
+			Live:       true,
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
@@ -115,6 +343,16 @@ func TestIndexes(t *testing.T) {
 			Type:    "btree",
 			Unique:  false,
 			Columns: []string{"major", "minor"},
+			Keys: []IndexKey{
+				{Column: "major"},
+				{Column: "minor"},
+			},
+			Definition: "CREATE INDEX index_indexed ON schemaspyint.indexed USING btree (major, minor)",
+			Immediate:  true,
+			Valid:      true,
+			Ready:      true,
+			Live:       true,
+			Clustered:  true,
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
@@ -128,14 +366,23 @@ func TestIndexes(t *testing.T) {
 			Table:   "indexed",
 			Type:    "btree",
 			Unique:  false,
-			Columns: []string{"[function]", "minor"},
+			Columns: []string{"lower((name)::text)", "minor"},
+			Keys: []IndexKey{
+				{Expression: "lower((name)::text)"},
+				{Column: "minor"},
+			},
+			Definition: "CREATE INDEX indexed_name_lower_idx ON schemaspyint.indexed USING btree (lower((name)::text), minor)",
+			Immediate:  true,
+			Valid:      true,
+			Ready:      true,
+			Live:       true,
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 
 	if have, want := d.Relations["indexed"].Indexes, []string{
-		"index_indexed", "indexed_name_lower_idx", "unique_indexed",
+		"index_indexed", "indexed_collated", "indexed_fancy", "indexed_name_lower_idx", "unique_indexed",
 	}; !reflect.DeepEqual(have, want) {
 		t.Fatalf("have %#v, want %#v", have, want)
 	}
@@ -149,31 +396,315 @@ func TestIndexes(t *testing.T) {
 			Unique:  true,
 			Primary: true,
 			Columns: []string{"id"},
+			Keys: []IndexKey{
+				{Column: "id"},
+			},
+			Definition:      "CREATE UNIQUE INDEX simple_pkey ON schemaspyint.simple USING btree (id)",
+			Immediate:       true,
+			Valid:           true,
+			Ready:           true,
+			Live:            true,
+			ReplicaIdentity: true,
+			Constraint:      "simple_pkey",
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 }
 
+func TestIndexDetails(t *testing.T) {
+	d := setup(t)
+
+	{
+		u := d.Indexes["indexed_fancy"]
+		if have, want := u, (Index{
+			Table:   "indexed",
+			Type:    "btree",
+			Columns: []string{"major", "name"},
+			Keys: []IndexKey{
+				{Column: "major", Descending: true},
+				{Column: "name", OpClass: "varchar_pattern_ops"},
+			},
+			Include:    []string{"minor"},
+			Predicate:  "(major > 0)",
+			Definition: "CREATE INDEX indexed_fancy ON schemaspyint.indexed USING btree (major DESC NULLS LAST, name varchar_pattern_ops) INCLUDE (minor) WHERE (major > 0)",
+			Immediate:  true,
+			Valid:      true,
+			Ready:      true,
+			Live:       true,
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		u := d.Indexes["indexed_collated"]
+		if have, want := u.Keys, []IndexKey{
+			{Column: "name", Collation: "C"},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestIndexHealth(t *testing.T) {
+	d := setup(t)
+
+	{
+		u := d.Indexes["broken_v"]
+		if have, want := u.Valid, false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.Unique, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		u := d.Indexes["unique_code"]
+		if have, want := u.Immediate, false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.Constraint, "unique_code"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	d := setup(t)
+	if have, want := sortedKeys(d.Constraints), []string{"constrained", "refunds", "simple"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := d.Relations["constrained"].Constraints, []string{
+		"constrained_pkey", "constrained_simple_id_fkey", "positive_amount", "unique_code",
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	{
+		c := d.Constraints["constrained"]["constrained_pkey"]
+		if have, want := c, (Constraint{
+			Type:       "primary key",
+			Table:      "constrained",
+			Columns:    []string{"id"},
+			Index:      "constrained_pkey",
+			Validated:  true,
+			Definition: "PRIMARY KEY (id)",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		c := d.Constraints["constrained"]["constrained_simple_id_fkey"]
+		if have, want := c, (Constraint{
+			Type:       "foreign key",
+			Table:      "constrained",
+			Columns:    []string{"simple_id"},
+			Index:      "simple_pkey",
+			RefTable:   QName{"schemaspyint", "simple"},
+			RefColumns: []string{"id"},
+			OnUpdate:   "no action",
+			OnDelete:   "cascade",
+			Validated:  true,
+			Definition: "FOREIGN KEY (simple_id) REFERENCES schemaspyint.simple(id) ON DELETE CASCADE",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		c := d.Constraints["constrained"]["positive_amount"]
+		if have, want := c, (Constraint{
+			Type:       "check",
+			Table:      "constrained",
+			Columns:    []string{"amount"},
+			Validated:  true,
+			Definition: "CHECK ((amount > 0))",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		c := d.Constraints["constrained"]["unique_code"]
+		if have, want := c, (Constraint{
+			Type:              "unique",
+			Table:             "constrained",
+			Columns:           []string{"code"},
+			Index:             "unique_code",
+			Deferrable:        true,
+			InitiallyDeferred: true,
+			Validated:         true,
+			Definition:        "UNIQUE (code) DEFERRABLE INITIALLY DEFERRED",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		c := d.Constraints["refunds"]["positive_amount"]
+		if have, want := c, (Constraint{
+			Type:       "check",
+			Table:      "refunds",
+			Columns:    []string{"amount"},
+			Validated:  true,
+			Definition: "CHECK ((amount > 0))",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	d := setup(t)
+
+	tab := d.Relations["simple"]
+	if have, want := len(tab.Triggers), 2; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Triggers["simple_audit"], (Trigger{
+		Timing:        "before",
+		Events:        []string{"insert", "update"},
+		UpdateColumns: []string{"name"},
+		Level:         "row",
+		When:          "(new.name IS NOT NULL)",
+		Enabled:       "origin",
+		Function:      "schemaspyint.audit",
+		Arguments:     []string{"a", "b"},
+		Definition:    "CREATE TRIGGER simple_audit BEFORE INSERT OR UPDATE OF name ON schemaspyint.simple FOR EACH ROW WHEN ((new.name IS NOT NULL)) EXECUTE FUNCTION schemaspyint.audit('a', 'b')",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Triggers["simple_log"], (Trigger{
+		Timing:     "after",
+		Events:     []string{"delete"},
+		Level:      "statement",
+		OldTable:   "gone",
+		Enabled:    "disabled",
+		Function:   "schemaspyint.audit",
+		Definition: "CREATE TRIGGER simple_log AFTER DELETE ON schemaspyint.simple REFERENCING OLD TABLE AS gone FOR EACH STATEMENT EXECUTE FUNCTION schemaspyint.audit()",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// quoted parenthesis
+	if have, want := d.Relations["refunds"].Triggers["refunds_audit"].When, "((new.amount)::text <> ')'::text)"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// foreign keys are implemented with internal triggers
+	if have, want := len(d.Relations["constrained"].Triggers), 0; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	d := setup(t)
+
+	tab := d.Relations["tenanted"]
+	if have, want := tab.RowSecurity, true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.ForceRowSecurity, true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Policies, map[string]Policy{
+		"tenant_isolation": {
+			Command:    "all",
+			Permissive: true,
+			Roles:      []string{"public"},
+			Using:      "(tenant = CURRENT_USER)",
+			WithCheck:  "(tenant = CURRENT_USER)",
+		},
+		"no_deletes": {
+			Command: "delete",
+			Roles:   []string{"public"},
+			Using:   "false",
+		},
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := d.Relations["simple"].RowSecurity, false; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestPrivileges(t *testing.T) {
+	d := setup(t)
+
+	owner := d.Owner
+	if owner == "" {
+		t.Fatal("no schema owner")
+	}
+	if have, want := Granted(d.Privileges, owner, "CREATE"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	{
+		tab := d.Relations["simple"]
+		if have, want := tab.Owner, owner; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := Granted(tab.Privileges, "public", "SELECT"), true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := Granted(tab.Privileges, "public", "INSERT"), false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := Granted(tab.Privileges, owner, "INSERT"), true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		col := d.Relations["indexed"].Columns["name"]
+		if have, want := col.Privileges, []Privilege{
+			{Grantee: "public", Privilege: "UPDATE", Grantor: owner},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	if have, want := Granted(d.Functions["audit()"].Privileges, "public", "EXECUTE"), false; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := Granted(d.Functions["proc(int4)"].Privileges, "public", "EXECUTE"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Sequences["countme"].Owner, owner; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := Granted(d.Sequences["countme"].Privileges, owner, "USAGE"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := Granted(d.Types["mood"].Privileges, "public", "USAGE"), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
 func TestViews(t *testing.T) {
 	d := setup(t)
 
-	if have, want := d.Views, []string{"myview_now"}; !reflect.DeepEqual(have, want) {
+	if have, want := d.Views, []string{"myview_checked", "myview_now"}; !reflect.DeepEqual(have, want) {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 
 	{
 		u := d.Relations["myview_now"]
+		u.Owner, u.Privileges = "", nil // see TestPrivileges
 		if have, want := u, (Relation{
-			Type: "view",
+			Type:       "view",
+			Definition: " SELECT id,\n    name\n   FROM schemaspyint.simple\n  WHERE (t > CURRENT_TIMESTAMP);",
 			Columns: map[string]Column{
 				"id": {
 					Type:     "uuid",
+					TypeName: QName{"pg_catalog", "uuid"},
+					FullType: "uuid",
 					Position: 1,
+					AttNum:   1,
 				},
 				"name": {
 					Type:     "text",
+					TypeName: QName{"pg_catalog", "text"},
+					FullType: "text",
 					Position: 2,
+					AttNum:   2,
 				},
 			},
 		}); !reflect.DeepEqual(have, want) {
@@ -184,22 +715,31 @@ func TestViews(t *testing.T) {
 
 func TestMaterialized(t *testing.T) {
 	d := setup(t)
-	if have, want := d.Materialized, []string{"myview_forever"}; !reflect.DeepEqual(have, want) {
+	if have, want := d.Materialized, []string{"myview_forever", "myview_later"}; !reflect.DeepEqual(have, want) {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 
 	{
 		u := d.Relations["myview_forever"]
+		u.Owner, u.Privileges = "", nil // see TestPrivileges
 		if have, want := u, (Relation{
-			Type: "materialized view",
+			Type:       "materialized view",
+			Definition: " SELECT id,\n    name\n   FROM schemaspyint.simple\n  WHERE (t > CURRENT_TIMESTAMP);",
+			Populated:  true,
 			Columns: map[string]Column{
 				"id": {
 					Type:     "uuid",
+					TypeName: QName{"pg_catalog", "uuid"},
+					FullType: "uuid",
 					Position: 1,
+					AttNum:   1,
 				},
 				"name": {
 					Type:     "text",
+					TypeName: QName{"pg_catalog", "text"},
+					FullType: "text",
 					Position: 2,
+					AttNum:   2,
 				},
 			},
 		}); !reflect.DeepEqual(have, want) {
@@ -208,76 +748,589 @@ func TestMaterialized(t *testing.T) {
 	}
 }
 
+func TestViewOptions(t *testing.T) {
+	d := setup(t)
+
+	{
+		u := d.Relations["myview_checked"]
+		if have, want := u.CheckOption, "local"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.SecurityBarrier, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.SecurityInvoker, false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		u := d.Relations["myview_later"]
+		if have, want := u.Populated, false; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := u.ConcurrentRefreshIndexes, []string{"myview_later_id"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
 func TestSequence(t *testing.T) {
 	d := setup(t)
-	if have, want := len(d.Sequences), 1; have != want {
+	if have, want := len(d.Sequences), 4; have != want {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 
 	{
 		s := d.Sequences["countme"]
+		s.Owner, s.Privileges = "", nil // see TestPrivileges
 		if have, want := s, (Sequence{
+			Type:        "bigint",
 			IncrementBy: 42,
 			MinValue:    4001,
 			MaxValue:    400100,
 			Start:       40010,
+			Cache:       1,
 			Cycle:       true,
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
+	{
+		s := d.Sequences["Order"]
+		s.Owner, s.Privileges = "", nil // see TestPrivileges
+		if have, want := s, (Sequence{
+			Type:          "smallint",
+			IncrementBy:   1,
+			MinValue:      1,
+			MaxValue:      32767,
+			Start:         1,
+			Cache:         10,
+			LastValue:     12,
+			IsCalled:      true,
+			OwnedByTable:  "constrained",
+			OwnedByColumn: "code",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		s := d.Sequences["defaulted_serial_id_seq"]
+		if have, want := s.Type, "integer"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := s.OwnedByTable, "defaulted"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := s.OwnedByColumn, "serial_id"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		// identity columns have their own sequence
+		s := d.Sequences["defaulted_id_seq"]
+		if have, want := s.OwnedByColumn, "id"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	d := setup(t)
+
+	if have, want := len(d.Types), 4; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	{
+		ty := d.Types["mood"]
+		ty.Owner, ty.Privileges = "", nil // see TestPrivileges
+		if have, want := ty, (Type{
+			Type:   "enum",
+			Labels: []string{"sad", "meh", "ok", "happy"},
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		ty := d.Types["address"]
+		ty.Owner, ty.Privileges = "", nil // see TestPrivileges
+		if have, want := ty, (Type{
+			Type: "composite",
+			Attributes: map[string]Column{
+				"street": {
+					Type:     "text",
+					TypeName: QName{"pg_catalog", "text"},
+					FullType: "text",
+					Position: 1,
+					AttNum:   1,
+				},
+				"number": {
+					Type:     "int4",
+					TypeName: QName{"pg_catalog", "int4"},
+					FullType: "integer",
+					Position: 2,
+					AttNum:   2,
+				},
+			},
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		ty := d.Types["posint"]
+		if have, want := ty.Type, "domain"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := ty.BaseType, "integer"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := ty.Default, "1"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := ty.NotNull, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := ty.Constraints["posint_check"], (Constraint{
+			Type:       "check",
+			Validated:  true,
+			Definition: "CHECK ((VALUE > 0))",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+	{
+		ty := d.Types["floatrange"]
+		ty.Owner, ty.Privileges = "", nil // see TestPrivileges
+		if have, want := ty, (Type{
+			Type:        "range",
+			Subtype:     "float8",
+			SubtypeDiff: "float8mi",
+			Multirange:  "floatmultirange",
+		}); !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
 }
 
 func TestFunctions(t *testing.T) {
 	d := setup(t)
-	if have, want := len(d.Functions), 3; have != want {
+	if have, want := len(d.Functions), 10; have != want {
 		t.Errorf("have %#v, want %#v", have, want)
 	}
 
 	{
-		s := d.Functions["my_first_sql_function"]
+		s := d.Functions["my_first_sql_function()"]
+		s.Owner, s.Privileges = "", nil // see TestPrivileges
 		if have, want := s, (Function{
+			Name:          "my_first_sql_function",
+			Kind:          "function",
 			Language:      "sql",
 			ArgumentTypes: []string(nil),
+			ReturnType:    "varchar",
+			Result:        "character varying",
+			Volatility:    "volatile",
+			Parallel:      "unsafe",
+			Cost:          100,
 			Src:           "\n    SELECT name FROM schemaspyint.indexed\n    WHERE minor < 0;\n",
+			Definition:    "CREATE OR REPLACE FUNCTION schemaspyint.my_first_sql_function()\n RETURNS character varying\n LANGUAGE sql\nAS $function$\n    SELECT name FROM schemaspyint.indexed\n    WHERE minor < 0;\n$function$\n",
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 
 	{
-		s := d.Functions["my_first_plpgsql_function"]
+		s := d.Functions["my_first_plpgsql_function(float4)"]
+		s.Owner, s.Privileges = "", nil // see TestPrivileges
 		if have, want := s, (Function{
+			Name:          "my_first_plpgsql_function",
+			Kind:          "function",
 			Language:      "plpgsql",
 			ArgumentTypes: []string{"float4"},
-			Src:           "\nBEGIN\n    RETURN subtotal * 0.06;\nEND;\n",
+			Arguments: []Argument{
+				{Name: "subtotal", Mode: "in", Type: "float4", TypeName: QName{"pg_catalog", "float4"}},
+			},
+			ReturnType: "float4",
+			Result:     "real",
+			Volatility: "volatile",
+			Parallel:   "unsafe",
+			Cost:       100,
+			Src:        "\nBEGIN\n    RETURN subtotal * 0.06;\nEND;\n",
+			Definition: "CREATE OR REPLACE FUNCTION schemaspyint.my_first_plpgsql_function(subtotal real)\n RETURNS real\n LANGUAGE plpgsql\nAS $function$\nBEGIN\n    RETURN subtotal * 0.06;\nEND;\n$function$\n",
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 
 	{
-		s := d.Functions["my_first_variadic_function"]
+		s := d.Functions["my_first_variadic_function(numeric[])"]
+		s.Owner, s.Privileges = "", nil // see TestPrivileges
 		if have, want := s, (Function{
+			Name:          "my_first_variadic_function",
+			Kind:          "function",
 			Language:      "sql",
 			ArgumentTypes: []string{"numeric[]"},
-			Src:           "\n    SELECT min($1[i]) FROM generate_subscripts($1, 1) g(i);\n",
+			Arguments: []Argument{
+				{Name: "arr", Mode: "variadic", Type: "numeric[]", TypeName: QName{"pg_catalog", "numeric"}, Array: true},
+			},
+			ReturnType: "numeric",
+			Result:     "numeric",
+			Volatility: "volatile",
+			Parallel:   "unsafe",
+			Cost:       100,
+			Src:        "\n    SELECT min($1[i]) FROM generate_subscripts($1, 1) g(i);\n",
+			Definition: "CREATE OR REPLACE FUNCTION schemaspyint.my_first_variadic_function(VARIADIC arr numeric[])\n RETURNS numeric\n LANGUAGE sql\nAS $function$\n    SELECT min($1[i]) FROM generate_subscripts($1, 1) g(i);\n$function$\n",
 		}); !reflect.DeepEqual(have, want) {
 			t.Errorf("have %#v, want %#v", have, want)
 		}
 	}
 }
 
-func mustDBPool(t *testing.T) *pgx.ConnPool {
-	cc, err := pgx.ParseURI(intPGURL)
+func TestFunctionDetails(t *testing.T) {
+	d := setup(t)
+
+	{
+		f := d.Functions["detailed(int4,text)"]
+		if have, want := f.Arguments, []Argument{
+			{Name: "a", Mode: "in", Type: "int4", TypeName: QName{"pg_catalog", "int4"}},
+			{Name: "b", Mode: "inout", Type: "text", TypeName: QName{"pg_catalog", "text"}, Default: "'hi'::text"},
+			{Name: "c", Mode: "out", Type: "int4", TypeName: QName{"pg_catalog", "int4"}},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.ReturnType, "record"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Volatility, "stable"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Strict, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.SecurityDefiner, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Parallel, "safe"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Cost, 5.0; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Config, []string{"search_path=schemaspyint"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+
+	{
+		// defaults are not parsed from pg_get_function_arguments()
+		f := d.Functions["quoted(int4,text,int4)"]
+		if have, want := f.Arguments, []Argument{
+			{Name: "a", Mode: "in", Type: "int4", TypeName: QName{"pg_catalog", "int4"}},
+			{Name: "b DEFAULT c", Mode: "in", Type: "text", TypeName: QName{"pg_catalog", "text"}, Default: "'x DEFAULT y, z'::text"},
+			{Name: "d", Mode: "in", Type: "int4", TypeName: QName{"pg_catalog", "int4"}, Default: "2"},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+
+	{
+		f := d.Functions["settable()"]
+		if have, want := f.Arguments, []Argument{
+			{Name: "id", Mode: "table", Type: "int4", TypeName: QName{"pg_catalog", "int4"}},
+			{Name: "name", Mode: "table", Type: "text", TypeName: QName{"pg_catalog", "text"}},
+		}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.ReturnsSet, true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Result, "TABLE(id integer, name text)"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+		if have, want := f.Rows, 1000.0; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+
+	{
+		f := d.Functions["proc(int4)"]
+		if have, want := f.Kind, "procedure"; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestOverloaded(t *testing.T) {
+	d := setup(t)
+
+	if have, want := d.FunctionsByName("overloaded"), []string{
+		"overloaded(int4)", "overloaded(text)",
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Functions["overloaded(text)"].ArgumentTypes, []string{"text"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.FunctionsByName("nosuchfunction"), []string(nil); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestComments(t *testing.T) {
+	d := setup(t)
+
+	if have, want := d.Comment, "integration tests"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	tab := d.Relations["tenanted"]
+	if have, want := tab.Comment, "one row per tenant"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["tenant"].Comment, "the owning role"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["data"].Comment, ""; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Indexes["indexed_collated"].Comment, "sorts bytewise"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Functions["proc(int4)"].Comment, "does nothing"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	db := mustDB(t)
+	ctx := context.Background()
+
+	d, err := DescribeDatabaseContext(ctx, db, "schemaspyint", "schemaspyint_other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := d.SchemaNames(), []string{"schemaspyint", "schemaspyint_other"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Schemas["schemaspyint"], setup(t); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := d.Schemas["schemaspyint_other"].Tables, []string{"elsewhere"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	all, err := DescribeDatabaseContext(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all.Schemas["schemaspyint_other"]; !ok {
+		t.Errorf("schema missing")
+	}
+	if _, ok := all.Schemas["pg_catalog"]; ok {
+		t.Errorf("system schema found")
+	}
+
+	if _, err := DescribeDatabaseContext(ctx, db, "nosuchschema"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestCrossSchema(t *testing.T) {
+	db := mustDB(t)
+
+	d, err := DescribeContext(context.Background(), db, "schemaspyint_other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab := d.Relations["elsewhere"]
+	if have, want := tab.Inherits, []QName{{"schemaspyint", "root"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["feeling"].TypeName, (QName{"schemaspyint", "mood"}); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := tab.Columns["feeling"].TypeName.String(), "schemaspyint.mood"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := len(tab.Constraints), 1; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	c := d.Constraints["elsewhere"][tab.Constraints[0]]
+	if have, want := c.RefTable, (QName{"schemaspyint", "simple"}); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := c.RefColumns, []string{"id"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	db := mustDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := DescribeContext(ctx, db, "schemaspyint")
+	var te *TimeoutError
+	if have, want := errors.As(err, &te), false; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := errors.Is(err, context.Canceled), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	_, err = DescribeContext(ctx, db, "schemaspyint")
+	if have, want := errors.As(err, &te), true; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	if have, want := errors.Is(err, context.DeadlineExceeded), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	ctx = context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := SetTimeouts(ctx, SQL(tx), Timeouts{Statement: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.ExecContext(ctx, "SELECT pg_sleep(1)")
+	if have, want := errors.As(asTimeout(ctx, err), &te), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+// sleeper runs a slow query before every query.
+type sleeper struct {
+	q Queryer
+}
+
+func (s sleeper) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	if err := exec(ctx, s.q, "SELECT pg_sleep(10)"); err != nil {
+		return nil, err
+	}
+	return s.q.Query(ctx, sql, args...)
+}
+
+func TestCancelQuery(t *testing.T) {
+	db := mustDB(t)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// no statement_timeout, the driver cancels the running query
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = Describe(ctx, sleeper{SQL(tx)}, "schemaspyint")
+	var te *TimeoutError
+	if have, want := errors.As(err, &te), true; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	if have, want := errors.Is(err, context.DeadlineExceeded), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("query was not cancelled, took %s", d)
+	}
+}
+
+func TestDescribeDeadline(t *testing.T) {
+	db := mustDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d, err := DescribeContext(ctx, db, "schemaspyint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := d, setup(t); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestDescribeConnTx(t *testing.T) {
+	db := mustDB(t)
+	ctx := context.Background()
+	want := setup(t)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	d, err := DescribeConnContext(ctx, conn, "schemaspyint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("have %#v, want %#v", d, want)
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
 	if err != nil {
 		t.Fatal(err)
 	}
-	db, err := pgx.NewConnPool(pgx.ConnPoolConfig{
-		ConnConfig: cc,
-	})
+	defer tx.Rollback()
+	all, err := DescribeDatabaseTxContext(ctx, tx, "schemaspyint")
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !reflect.DeepEqual(all.Schemas["schemaspyint"], want) {
+		t.Errorf("have %#v, want %#v", all.Schemas["schemaspyint"], want)
+	}
+}
+
+func TestDescribeDDL(t *testing.T) {
+	d := setup(t)
+
+	ddl := d.DDL()
+	for _, want := range []string{
+		"CREATE SCHEMA schemaspyint;\n",
+		"CREATE TYPE schemaspyint.mood AS ENUM ('sad', 'meh', 'ok', 'happy');\n",
+		"CREATE SEQUENCE schemaspyint.\"Order\" AS smallint INCREMENT BY 1 MINVALUE 1 MAXVALUE 32767 START WITH 1 CACHE 10;\n",
+		"CREATE TABLE schemaspyint.measurement_2020 PARTITION OF schemaspyint.measurement FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');\n",
+		"CREATE UNIQUE INDEX unique_indexed ON schemaspyint.indexed USING btree (name);\n",
+		"ALTER TABLE schemaspyint.constrained ADD CONSTRAINT unique_code UNIQUE (code) DEFERRABLE INITIALLY DEFERRED;\n",
+		"ALTER TABLE schemaspyint.simple DISABLE TRIGGER simple_log;\n",
+		"COMMENT ON TABLE schemaspyint.tenanted IS 'one row per tenant';\n",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if have, want := strings.Index(ddl, "CREATE TABLE schemaspyint.simple "), strings.Index(ddl, "CREATE VIEW schemaspyint.myview_now "); have > want {
+		t.Errorf("view before its table")
+	}
+	if have, want := setup(t).DDL(), ddl; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func mustDB(t *testing.T) *sql.DB {
+	db, err := sql.Open(*intDriver, intPGURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
 	return db
 }
+
+func TestDescribeSaveLoad(t *testing.T) {
+	d := setup(t)
+
+	var b bytes.Buffer
+	if err := Save(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Load(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, again) {
+		t.Errorf("round trip changes:\n%s", Diff(d, again))
+	}
+}
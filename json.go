@@ -0,0 +1,80 @@
+package schemaspy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatVersion is the version of the JSON format written by Save().
+const FormatVersion = 1
+
+// file is the top level of the JSON format
+type file struct {
+	Version int             `json:"version"`
+	Schema  json.RawMessage `json:"schema"`
+}
+
+// Save writes the schema as JSON, in a format which Load() reads back into
+// the same Schema. It's meant to store snapshots of a schema, which can be
+// compared later with Diff().
+//
+// The format is an object with a "version" (FormatVersion) and a "schema":
+//
+//	{
+//	  "schema": {
+//	    "comment": "",
+//	    "constraints": {...},
+//	    ...
+//	  },
+//	  "version": 1
+//	}
+//
+// The keys are the json tags of the Schema structs, which are the field
+// names in snake_case, such as "partition_of". Every field is written, also
+// the empty ones. All object keys are sorted, and the output is indented, so the
+// same Schema always gives the same bytes, and the files diff well. The
+// version will only change when a field is renamed or changes meaning; new
+// fields don't change it.
+func Save(w io.Writer, s *Schema) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// structs are written in field order, maps are sorted. Going via maps
+	// sorts everything.
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"version": FormatVersion,
+		"schema":  v,
+	})
+}
+
+// Load reads a schema written by Save(). Files with a newer version are an
+// error.
+func Load(r io.Reader) (*Schema, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	switch {
+	case f.Version == 0 || f.Schema == nil:
+		return nil, fmt.Errorf("not a schemaspy file")
+	case f.Version > FormatVersion:
+		return nil, fmt.Errorf("unsupported format version %d", f.Version)
+	}
+	var s Schema
+	if err := json.Unmarshal(f.Schema, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
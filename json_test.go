@@ -0,0 +1,108 @@
+package schemaspy
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	s, err := ParseDDLFiles("schemaspyint", "./test_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Owner = "alice"
+	s.Privileges = []Privilege{{Grantee: "bob", Privilege: "USAGE"}}
+	s.Sequences["countme"] = Sequence{Type: "bigint", MaxValue: 1<<63 - 1, LastValue: 42, IsCalled: true}
+
+	var b bytes.Buffer
+	if err := Save(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	saved := b.String()
+	again, err := Load(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, again) {
+		t.Errorf("round trip changes:\n%s", Diff(s, again))
+	}
+
+	// same schema, same bytes
+	b.Reset()
+	if err := Save(&b, again); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := b.String(), saved; have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+	if !strings.HasPrefix(saved, "{\n  \"schema\": {\n    \"comment\": \"integration tests\",\n") {
+		t.Errorf("have %s", saved[:80])
+	}
+	if !strings.HasSuffix(saved, "\n  \"version\": 1\n}\n") {
+		t.Errorf("have %s", saved[len(saved)-80:])
+	}
+	if !strings.Contains(saved, `"predicate": "(major > 0)"`) {
+		t.Errorf("HTML escaping is on")
+	}
+	checkSorted(t, json.NewDecoder(strings.NewReader(saved)))
+}
+
+// checkSorted checks the keys of all objects are sorted.
+func checkSorted(t *testing.T, d *json.Decoder) {
+	t.Helper()
+	tok, err := d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch tok {
+	case json.Delim('{'):
+		var keys []string
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, k.(string))
+			checkSorted(t, d)
+		}
+		if !sort.StringsAreSorted(keys) {
+			t.Errorf("keys not sorted: %v", keys)
+		}
+		d.Token()
+	case json.Delim('['):
+		for d.More() {
+			checkSorted(t, d)
+		}
+		d.Token()
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for js, want := range map[string]string{
+		`{"version": 2, "schema": {}}`: "unsupported format version 2",
+		`{"name": "public"}`:           "not a schemaspy file",
+		`{"version": 1, "schema": []}`: "json: cannot unmarshal array into Go value of type schemaspy.Schema",
+		`{"version": 1, `:              "unexpected EOF",
+	} {
+		_, err := Load(strings.NewReader(js))
+		if err == nil {
+			t.Errorf("%s: no error", js)
+			continue
+		}
+		if have := err.Error(); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+
+	s, err := Load(strings.NewReader(`{"version": 1, "schema": {"name": "public"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := s.Name, "public"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
@@ -0,0 +1,549 @@
+package schemaspy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokIdent  tokenType = iota // unquoted identifiers and keywords, lowercased
+	tokQuoted                  // "quoted identifiers"
+	tokString                  // 'strings', E'strings', and $$dollar quoted$$ strings
+	tokNumber
+	tokParam // $1
+	tokOp    // operators and punctuation
+)
+
+type token struct {
+	typ tokenType
+	// text is the lowercased identifier, the unquoted identifier or string,
+	// or the operator
+	text string
+	// raw is the token as written
+	raw string
+	// space is true if the token was preceded by whitespace or a comment
+	space bool
+	line  int
+}
+
+// is matches unquoted keywords, and operators
+func (t token) is(word string) bool {
+	return (t.typ == tokIdent || t.typ == tokOp) && t.text == word
+}
+
+const opChars = "+-*/<>=~!@#%^&|`?"
+
+// lex splits SQL into statements, and the statements into tokens. Comments
+// are dropped.
+func lex(sql string) ([][]token, error) {
+	var (
+		stmts [][]token
+		cur   []token
+		line  = 1
+		space = false
+		depth = 0
+	)
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start, startLine := i, line
+		switch {
+		case c == '\n':
+			line++
+			space = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			space = true
+			i++
+			continue
+		case strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			nest := 0
+			for i < len(sql) {
+				switch {
+				case strings.HasPrefix(sql[i:], "/*"):
+					nest++
+					i += 2
+				case strings.HasPrefix(sql[i:], "*/"):
+					nest--
+					i += 2
+				default:
+					if sql[i] == '\n' {
+						line++
+					}
+					i++
+				}
+				if nest == 0 {
+					break
+				}
+			}
+			if nest > 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", startLine)
+			}
+			space = true
+			continue
+		}
+
+		var t token
+		switch {
+		case c == ';' && depth == 0:
+			if len(cur) > 0 {
+				stmts = append(stmts, cur)
+			}
+			cur = nil
+			space = false
+			i++
+			continue
+		case c == '\'' || ((c == 'e' || c == 'E') && i+1 < len(sql) && sql[i+1] == '\''):
+			escapes := c != '\''
+			if escapes {
+				i++
+			}
+			s, n, err := lexString(sql[i:], escapes)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", startLine, err)
+			}
+			t = token{typ: tokString, text: s}
+			i += n
+		case c == '"':
+			s, n, err := lexQuoted(sql[i:], '"')
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", startLine, err)
+			}
+			if s == "" {
+				return nil, fmt.Errorf("line %d: zero-length delimited identifier", startLine)
+			}
+			t = token{typ: tokQuoted, text: s}
+			i += n
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			i++
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+			t = token{typ: tokParam}
+		case c == '$':
+			end := strings.IndexByte(sql[i+1:], '$')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated dollar quote", startLine)
+			}
+			tag := sql[i : i+end+2]
+			body := strings.Index(sql[i+len(tag):], tag)
+			if body < 0 {
+				return nil, fmt.Errorf("line %d: unterminated dollar quote", startLine)
+			}
+			t = token{typ: tokString, text: sql[i+len(tag) : i+len(tag)+body]}
+			i += 2*len(tag) + body
+		case isIdentStart(c):
+			for i < len(sql) && (isIdentStart(sql[i]) || isDigit(sql[i]) || sql[i] == '$') {
+				i++
+			}
+			t = token{typ: tokIdent, text: strings.ToLower(sql[start:i])}
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+				i++
+			}
+			if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+				i++
+				if i < len(sql) && (sql[i] == '+' || sql[i] == '-') {
+					i++
+				}
+				for i < len(sql) && isDigit(sql[i]) {
+					i++
+				}
+			}
+			t = token{typ: tokNumber, text: sql[start:i]}
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			i += 2
+			t = token{typ: tokOp, text: "::"}
+		case strings.IndexByte(opChars, c) >= 0:
+			for i < len(sql) && strings.IndexByte(opChars, sql[i]) >= 0 && !strings.HasPrefix(sql[i:], "--") && !strings.HasPrefix(sql[i:], "/*") {
+				i++
+			}
+			t = token{typ: tokOp, text: sql[start:i]}
+		default:
+			switch c {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				depth--
+			}
+			i++
+			t = token{typ: tokOp, text: string(c)}
+		}
+		for _, r := range sql[start:i] {
+			if r == '\n' {
+				line++
+			}
+		}
+		t.raw = sql[start:i]
+		t.space = space
+		t.line = startLine
+		space = false
+		cur = append(cur, t)
+	}
+	if len(cur) > 0 {
+		stmts = append(stmts, cur)
+	}
+	return stmts, nil
+}
+
+// lexString reads a 'string', with ” escapes, and with backslash escapes if
+// escapes is set. It returns the value, and the length.
+func lexString(s string, escapes bool) (string, int, error) {
+	if !escapes {
+		return lexQuoted(s, '\'')
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) {
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(s[i])
+				}
+			}
+		case '\'':
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexQuoted reads a string or identifier quoted with q, where q is escaped
+// by doubling it.
+func lexQuoted(s string, q byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] == q {
+			if i+1 < len(s) && s[i+1] == q {
+				b.WriteByte(q)
+				i++
+				continue
+			}
+			return b.String(), i + 1, nil
+		}
+		b.WriteByte(s[i])
+	}
+	return "", 0, fmt.Errorf("unterminated %c", q)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// joinTokens gives the SQL of the tokens, with all whitespace and comments
+// between tokens replaced by a single space.
+func joinTokens(ts []token) string {
+	var b strings.Builder
+	for i, t := range ts {
+		if i > 0 && t.space {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.raw)
+	}
+	return b.String()
+}
+
+// cursor walks over the tokens of a statement
+type cursor struct {
+	ts []token
+	i  int
+}
+
+func (c *cursor) eof() bool {
+	return c.i >= len(c.ts)
+}
+
+// peek gives the current token, or the zero token at the end.
+func (c *cursor) peek() token {
+	return c.peekAt(0)
+}
+
+// peekAt gives the token n places ahead.
+func (c *cursor) peekAt(n int) token {
+	if c.i+n >= len(c.ts) {
+		return token{typ: -1}
+	}
+	return c.ts[c.i+n]
+}
+
+func (c *cursor) next() token {
+	t := c.peek()
+	if !c.eof() {
+		c.i++
+	}
+	return t
+}
+
+// at is true if the next tokens are the keywords or operators.
+func (c *cursor) at(words ...string) bool {
+	for i, w := range words {
+		if !c.peekAt(i).is(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// accept skips over the words if they are next.
+func (c *cursor) accept(words ...string) bool {
+	if !c.at(words...) {
+		return false
+	}
+	c.i += len(words)
+	return true
+}
+
+func (c *cursor) expect(words ...string) error {
+	if !c.accept(words...) {
+		return c.errorf("expected %q", strings.ToUpper(strings.Join(words, " ")))
+	}
+	return nil
+}
+
+// expectToken checks the type of a token which was read with next().
+func (c *cursor) expectToken(t token, typ tokenType) error {
+	if t.typ != typ {
+		c.i--
+		return c.errorf("unexpected input")
+	}
+	return nil
+}
+
+func (c *cursor) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if c.eof() {
+		line := 0
+		if len(c.ts) > 0 {
+			line = c.ts[len(c.ts)-1].line
+		}
+		return fmt.Errorf("line %d: %s, at end of statement", line, msg)
+	}
+	t := c.peek()
+	return fmt.Errorf("line %d: %s, at %q", t.line, msg, t.raw)
+}
+
+// ident reads an identifier, quoted or not.
+func (c *cursor) ident() (string, error) {
+	t := c.peek()
+	if t.typ != tokIdent && t.typ != tokQuoted {
+		return "", c.errorf("expected a name")
+	}
+	c.i++
+	return t.text, nil
+}
+
+// dotted reads a name with any number of dots, such as "a.b.c".
+func (c *cursor) dotted() ([]string, error) {
+	var parts []string
+	for {
+		p, err := c.ident()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+		if !c.accept(".") {
+			return parts, nil
+		}
+	}
+}
+
+// qname reads a name which can have a schema.
+func (c *cursor) qname() (QName, error) {
+	parts, err := c.dotted()
+	if err != nil {
+		return QName{}, err
+	}
+	switch len(parts) {
+	case 1:
+		return QName{Name: parts[0]}, nil
+	case 2:
+		return QName{Schema: parts[0], Name: parts[1]}, nil
+	default:
+		return QName{}, c.errorf("invalid name %q", strings.Join(parts, "."))
+	}
+}
+
+// number reads an integer, which can be negative.
+func (c *cursor) number() (int, error) {
+	neg := c.accept("-")
+	t := c.peek()
+	n, err := strconv.Atoi(t.text)
+	if t.typ != tokNumber || err != nil {
+		return 0, c.errorf("expected an integer")
+	}
+	c.i++
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+func (c *cursor) float() (float64, error) {
+	t := c.peek()
+	f, err := strconv.ParseFloat(t.text, 64)
+	if t.typ != tokNumber || err != nil {
+		return 0, c.errorf("expected a number")
+	}
+	c.i++
+	return f, nil
+}
+
+// group reads a (...) or [...] group, and gives what's in it.
+func (c *cursor) group() ([]token, error) {
+	if !c.at("(") && !c.at("[") {
+		return nil, c.errorf("expected %q", "(")
+	}
+	start := c.i
+	depth := 0
+	for ; c.i < len(c.ts); c.i++ {
+		switch t := c.ts[c.i]; {
+		case t.is("(") || t.is("["):
+			depth++
+		case t.is(")") || t.is("]"):
+			depth--
+		}
+		if depth == 0 {
+			c.i++
+			return c.ts[start+1 : c.i-1], nil
+		}
+	}
+	c.i = start
+	return nil, c.errorf("unbalanced parenthesis")
+}
+
+// identList reads a (...) list of identifiers.
+func (c *cursor) identList() ([]string, error) {
+	g, err := c.group()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	list, err := splitTokens(g)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range list {
+		if len(n) != 1 || (n[0].typ != tokIdent && n[0].typ != tokQuoted) {
+			return nil, c.errorf("expected a list of names")
+		}
+		names = append(names, n[0].text)
+	}
+	return names, nil
+}
+
+// until reads up to the first token, outside parenthesis, for which stop is
+// true.
+func (c *cursor) until(stop func(token) bool) []token {
+	start := c.i
+	depth := 0
+	for ; c.i < len(c.ts); c.i++ {
+		t := c.ts[c.i]
+		if depth == 0 && stop(t) {
+			break
+		}
+		switch {
+		case t.is("(") || t.is("["):
+			depth++
+		case t.is(")") || t.is("]"):
+			depth--
+		}
+	}
+	return c.ts[start:c.i]
+}
+
+// rest reads everything which is left.
+func (c *cursor) rest() []token {
+	ts := c.ts[c.i:]
+	c.i = len(c.ts)
+	return ts
+}
+
+// splitTokens splits on commas outside parenthesis. An empty part, as in
+// "a,,b" or "a,", is an error at the comma.
+func splitTokens(ts []token) ([][]token, error) {
+	var (
+		parts [][]token
+		start int
+		depth int
+	)
+	for i, t := range ts {
+		switch {
+		case t.is("(") || t.is("["):
+			depth++
+		case t.is(")") || t.is("]"):
+			depth--
+		case t.is(",") && depth == 0:
+			if i == start {
+				return nil, (&cursor{ts: ts, i: i}).errorf("expected a list element")
+			}
+			parts = append(parts, ts[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(ts) {
+		parts = append(parts, ts[start:])
+	} else if start > 0 {
+		return nil, (&cursor{ts: ts, i: start - 1}).errorf("expected a list element")
+	}
+	return parts, nil
+}
+
+// options reads storage parameters, such as "(fillfactor = 70,
+// security_barrier)", as "key=value" strings. Keys without a value are
+// "true".
+func options(c *cursor) ([]string, error) {
+	g, err := c.group()
+	if err != nil {
+		return nil, err
+	}
+	var opts []string
+	list, err := splitTokens(g)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range list {
+		oc := &cursor{ts: o}
+		k := joinTokens(oc.until(func(t token) bool { return t.is("=") }))
+		if k == "" {
+			return nil, c.errorf("expected an option")
+		}
+		v := "true"
+		if oc.accept("=") {
+			vs := oc.rest()
+			v = joinTokens(vs)
+			if len(vs) == 1 && vs[0].typ != tokOp {
+				v = vs[0].text
+			}
+		}
+		opts = append(opts, k+"="+v)
+	}
+	return opts, nil
+}
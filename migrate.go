@@ -0,0 +1,1047 @@
+package schemaspy
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Statement is a single SQL statement of a Migration.
+type Statement struct {
+	// SQL is the statement, without the trailing ";"
+	SQL string
+	// Destructive statements can lose data, such as DROP TABLE, DROP COLUMN,
+	// or changing the type of a column.
+	Destructive bool
+	// Requires is the SQL of the destructive statement this statement goes
+	// with, such as the DROP TABLE of a table which is created again. Safe()
+	// leaves out both.
+	Requires string
+}
+
+// Migration is a list of statements, in the order they should run.
+type Migration []Statement
+
+// Safe returns the migration without the destructive statements, and without
+// the statements which require those. The result might not get you all the
+// way to the wanted schema.
+func (m Migration) Safe() Migration {
+	destructive := map[string]bool{}
+	for _, s := range m {
+		if s.Destructive {
+			destructive[s.SQL] = true
+		}
+	}
+	var res Migration
+	for _, s := range m {
+		if !s.Destructive && !destructive[s.Requires] {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Destructive returns only the destructive statements.
+func (m Migration) Destructive() Migration {
+	var res Migration
+	for _, s := range m {
+		if s.Destructive {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// String gives the migration as a SQL script. Destructive statements are
+// preceded by a "-- destructive" comment.
+func (m Migration) String() string {
+	var b strings.Builder
+	for _, s := range m {
+		if s.Destructive {
+			b.WriteString("-- destructive\n")
+		}
+		b.WriteString(s.SQL + ";\n")
+	}
+	return b.String()
+}
+
+// The order in which statements run. Everything which depends on an object
+// is dropped before the object, and created after it.
+const (
+	phaseDropEventTrigger = iota
+	phaseDropTrigger      // and policies
+	phaseDropForeignKey
+	phaseDropConstraint
+	phaseDropIndex
+	phaseDropView
+	phaseDetach
+	phaseDropTable
+	phaseDropFunction
+	phaseDropType
+	phaseType
+	phaseSequence
+	phaseFunction
+	phaseTable
+	phaseColumn       // and other ALTER TABLEs
+	phaseDropSequence // after the column defaults which use them
+	phaseAttach
+	phaseOwnedBy
+	phaseView
+	phaseIndex
+	phaseConstraint
+	phaseForeignKey
+	phaseIndexOptions
+	phaseTrigger // and policies
+	phaseEventTrigger
+	phaseComment
+)
+
+// Migrate gives the statements which change schema from into schema to,
+// such as from the live database into the wanted state. Either can be nil,
+// which is the same as an empty schema. Names are qualified with the name of
+// from, or of to if that's empty. References in to to its own schema, also
+// in SQL such as Index.Definition, are moved to the schema of from.
+//
+// Objects are dropped before the objects they depend on, and created after
+// them. Views which use a dropped relation, or a column which is dropped or
+// changes type, are dropped and created again. Objects which can't be
+// changed with an ALTER statement are dropped and created again.
+//
+// Owners, privileges, and aggregate functions are not migrated. See Diff()
+// for which fields are compared.
+func Migrate(from, to *Schema) Migration {
+	if from == nil {
+		from = &Schema{}
+	}
+	if to == nil {
+		to = &Schema{}
+	}
+	schema := from.Name
+	if schema == "" {
+		schema = to.Name
+	} else if to.Name != "" && to.Name != schema {
+		// everything in to is created in from's schema
+		moved := newLocal(to.Name).rename(reflect.ValueOf(*to), schema).Interface().(Schema)
+		moved.Name = schema
+		to = &moved
+	}
+	m := &migrator{
+		renderer: renderer{schema},
+		from:     from,
+		to:       to,
+		changes:  map[objectKey][]Change{},
+	}
+	for _, c := range Diff(from, to) {
+		k := objectKey{c.Object, c.Table, c.Name}
+		if _, ok := m.changes[k]; !ok {
+			m.order = append(m.order, k)
+		}
+		m.changes[k] = append(m.changes[k], c)
+	}
+	m.plan()
+	sort.SliceStable(m.stmts, func(i, j int) bool {
+		return m.stmts[i].phase < m.stmts[j].phase
+	})
+	var res Migration
+	for _, s := range m.stmts {
+		res = append(res, s.Statement)
+	}
+	return res
+}
+
+type objectKey struct {
+	object      Object
+	table, name string
+}
+
+type migrator struct {
+	renderer
+	from, to *Schema
+	// changes grouped by object, in Diff() order
+	changes map[objectKey][]Change
+	order   []objectKey
+	// relations which are dropped, and which are created, including the
+	// ones which are dropped and created again
+	dropped map[string]bool
+	created map[string]bool
+	// the DROP TABLE of tables which are created again
+	recreated map[string]string
+	stmts     []phasedStatement
+}
+
+type phasedStatement struct {
+	phase int
+	Statement
+}
+
+func (m *migrator) add(phase int, sql string) {
+	m.stmts = append(m.stmts, phasedStatement{phase, Statement{SQL: sql}})
+}
+
+func (m *migrator) addDestructive(phase int, sql string) {
+	m.stmts = append(m.stmts, phasedStatement{phase, Statement{SQL: sql, Destructive: true}})
+}
+
+// addFor adds a statement for a table, which goes with the DROP TABLE if the
+// table is created again.
+func (m *migrator) addFor(table string, phase int, sql string) {
+	m.addRequires(phase, sql, m.recreated[table])
+}
+
+func (m *migrator) addRequires(phase int, sql, requires string) {
+	m.stmts = append(m.stmts, phasedStatement{phase, Statement{SQL: sql, Requires: requires}})
+}
+
+func (m *migrator) plan() {
+	m.planRelations()
+	for _, k := range m.order {
+		cs := m.changes[k]
+		switch k.object {
+		case ObjectSchema:
+			if c, ok := field(cs, "Comment"); ok && m.schema != "" {
+				m.add(phaseComment, commentOn("SCHEMA", quoteIdent(m.schema), c.New.(string)))
+			}
+		case ObjectType:
+			m.planType(k.name, cs)
+		case ObjectSequence:
+			m.planSequence(k.name, cs)
+		case ObjectFunction:
+			m.planFunction(k.name, cs)
+		case ObjectColumn:
+			m.planColumn(k.table, k.name, cs)
+		case ObjectEventTrigger:
+			m.planEventTrigger(k.name, cs)
+		}
+	}
+	m.planIndexes()
+	m.planConstraints()
+	m.planTriggers()
+	m.planPolicies()
+}
+
+// field finds the change of a field
+func field(cs []Change, name string) (Change, bool) {
+	for _, c := range cs {
+		if c.Field == name {
+			return c, true
+		}
+	}
+	return Change{}, false
+}
+
+// onlyFields is true if all changes are for the given fields
+func onlyFields(cs []Change, names ...string) bool {
+	for _, c := range cs {
+		if c.Kind != Changed {
+			return false
+		}
+		ok := false
+		for _, n := range names {
+			if c.Field == n {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func kind(cs []Change) ChangeKind {
+	if len(cs) == 0 {
+		return ""
+	}
+	return cs[0].Kind
+}
+
+func (m *migrator) relationChanges(name string) []Change {
+	return m.changes[objectKey{ObjectRelation, "", name}]
+}
+
+// planRelations drops and creates tables and views, and alters tables.
+func (m *migrator) planRelations() {
+	m.dropped = map[string]bool{}
+	m.created = map[string]bool{}
+	m.recreated = map[string]string{}
+	// relations whose columns can't be used by views while they change
+	touched := map[string]bool{}
+	for _, n := range keys(m.from.Relations, m.to.Relations) {
+		cs := m.relationChanges(n)
+		switch kind(cs) {
+		case Added:
+			m.created[n] = true
+		case Removed:
+			m.dropped[n] = true
+		case Changed:
+			old := m.from.Relations[n]
+			if (isView(old) && !onlyFields(cs, "Comment", "Owner", "Privileges")) ||
+				!onlyFields(cs, "Comment", "Owner", "Privileges", "Options", "Inherits", "RowSecurity", "ForceRowSecurity", "PartitionOf", "PartitionBound") {
+				m.dropped[n] = true
+				m.created[n] = true
+			}
+		}
+		if m.dropped[n] {
+			touched[n] = true
+		}
+	}
+	for _, k := range m.order {
+		if k.object != ObjectColumn {
+			continue
+		}
+		cs := m.changes[k]
+		if _, ok := field(cs, "FullType"); ok || kind(cs) == Removed {
+			touched[k.table] = true
+		}
+		if _, ok := field(cs, "Generated"); ok {
+			touched[k.table] = true
+		}
+	}
+	// views which use touched relations are dropped and created again
+	for again := true; again; {
+		again = false
+		for _, n := range keys(m.from.Relations, m.from.Relations) {
+			r := m.from.Relations[n]
+			if !isView(r) || m.dropped[n] {
+				continue
+			}
+			if _, ok := m.to.Relations[n]; !ok {
+				continue
+			}
+			uses := mentions(r.Definition)
+			for t := range touched {
+				if uses(t) {
+					m.dropped[n] = true
+					m.created[n] = true
+					touched[n] = true
+					again = true
+					break
+				}
+			}
+		}
+	}
+
+	// drop
+	dropOrder := dependencyOrder(setNames(m.dropped), relationDeps(m.from))
+	for i := len(dropOrder) - 1; i >= 0; i-- {
+		n := dropOrder[i]
+		r := m.from.Relations[n]
+		sql := fmt.Sprintf("DROP %s %s", relationKind(r), m.name(n))
+		if isView(r) {
+			m.add(phaseDropView, sql)
+		} else {
+			m.addDestructive(phaseDropTable, sql)
+			if m.created[n] {
+				m.recreated[n] = sql
+			}
+		}
+	}
+
+	// create
+	for _, n := range dependencyOrder(setNames(m.created), relationDeps(m.to)) {
+		r := m.to.Relations[n]
+		if isView(r) {
+			m.add(phaseView, m.createView(n, r))
+		} else {
+			m.addFor(n, phaseTable, m.createTable(n, r))
+			m.tableOptions(n, Relation{}, r)
+		}
+		if r.Comment != "" {
+			m.addFor(n, phaseComment, commentOn(relationKind(r), m.name(n), r.Comment))
+		}
+		if !isView(r) || r.Type == "materialized view" {
+			for _, c := range r.ColumnNames() {
+				if col := r.Columns[c]; col.Comment != "" {
+					m.addFor(n, phaseComment, commentOn("COLUMN", m.name(n)+"."+quoteIdent(c), col.Comment))
+				}
+			}
+		}
+	}
+
+	// alter
+	for _, n := range keys(m.from.Relations, m.to.Relations) {
+		cs := m.relationChanges(n)
+		if kind(cs) != Changed || m.created[n] {
+			continue
+		}
+		old, r := m.from.Relations[n], m.to.Relations[n]
+		m.tableOptions(n, old, r)
+		if _, ok := field(cs, "Inherits"); ok {
+			for _, p := range old.Inherits {
+				if !hasQName(r.Inherits, p) {
+					m.addFor(m.local(p), phaseDetach, fmt.Sprintf("ALTER TABLE %s NO INHERIT %s", m.name(n), p))
+				}
+			}
+			for _, p := range r.Inherits {
+				if !hasQName(old.Inherits, p) {
+					m.addFor(m.local(p), phaseAttach, fmt.Sprintf("ALTER TABLE %s INHERIT %s", m.name(n), p))
+				}
+			}
+		}
+		_, ok1 := field(cs, "PartitionOf")
+		_, ok2 := field(cs, "PartitionBound")
+		if ok1 || ok2 {
+			if p := old.PartitionOf; p.Name != "" {
+				m.addFor(m.local(p), phaseDetach, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", p, m.name(n)))
+			}
+			if p := r.PartitionOf; p.Name != "" {
+				m.addFor(m.local(p), phaseAttach, fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s", p, m.name(n), r.PartitionBound))
+			}
+		}
+		if c, ok := field(cs, "Comment"); ok {
+			m.add(phaseComment, commentOn(relationKind(r), m.name(n), c.New.(string)))
+		}
+	}
+}
+
+// local gives the name of a relation in the migrated schema, or "".
+func (m *migrator) local(q QName) string {
+	if q.Schema == "" || q.Schema == m.schema {
+		return q.Name
+	}
+	return ""
+}
+
+// tableOptions changes the storage parameters and row level security of a
+// table.
+func (m *migrator) tableOptions(name string, old, r Relation) {
+	if isView(r) {
+		return
+	}
+	var set, reset []string
+	for _, o := range r.Options {
+		if !hasString(old.Options, o) {
+			set = append(set, o)
+		}
+	}
+	for _, o := range old.Options {
+		k := strings.SplitN(o, "=", 2)[0]
+		if relOption(r.Options, k) == "" {
+			reset = append(reset, k)
+		}
+	}
+	if len(set) > 0 && old.Type != "" {
+		m.addFor(name, phaseColumn, fmt.Sprintf("ALTER TABLE %s SET (%s)", m.name(name), strings.Join(set, ", ")))
+	}
+	if len(reset) > 0 {
+		m.addFor(name, phaseColumn, fmt.Sprintf("ALTER TABLE %s RESET (%s)", m.name(name), strings.Join(reset, ", ")))
+	}
+	if old.RowSecurity != r.RowSecurity {
+		m.addFor(name, phaseColumn, fmt.Sprintf("ALTER TABLE %s %s ROW LEVEL SECURITY", m.name(name), enable(r.RowSecurity)))
+	}
+	if old.ForceRowSecurity != r.ForceRowSecurity {
+		force := "FORCE"
+		if !r.ForceRowSecurity {
+			force = "NO FORCE"
+		}
+		m.addFor(name, phaseColumn, fmt.Sprintf("ALTER TABLE %s %s ROW LEVEL SECURITY", m.name(name), force))
+	}
+}
+
+func enable(b bool) string {
+	if b {
+		return "ENABLE"
+	}
+	return "DISABLE"
+}
+
+func (m *migrator) planColumn(table, name string, cs []Change) {
+	r := m.to.Relations[table]
+	if m.created[table] || isView(r) || m.fromParent(table, func(parent string) bool {
+		return sameChanges(m.changes[objectKey{ObjectColumn, parent, name}], cs)
+	}) {
+		return
+	}
+	var (
+		t   = m.name(table)
+		col = quoteIdent(name)
+		c   = r.Columns[name]
+	)
+	switch kind(cs) {
+	case Added:
+		m.add(phaseColumn, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", t, columnDef(name, c)))
+		if c.Comment != "" {
+			m.add(phaseComment, commentOn("COLUMN", t+"."+col, c.Comment))
+		}
+		return
+	case Removed:
+		m.addDestructive(phaseColumn, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", t, col))
+		return
+	}
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", t, col)
+	if ch, ok := field(cs, "Generated"); ok {
+		if ch.New == "" {
+			m.add(phaseColumn, alter+"DROP EXPRESSION")
+		} else {
+			// can't be changed in place
+			m.addDestructive(phaseColumn, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", t, col))
+			m.addDestructive(phaseColumn, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", t, columnDef(name, c)))
+			return
+		}
+	}
+	if _, ok := field(cs, "FullType"); ok {
+		m.addDestructive(phaseColumn, alter+"TYPE "+c.FullType)
+	}
+	if _, ok := field(cs, "Default"); ok {
+		if c.Default == "" {
+			m.add(phaseColumn, alter+"DROP DEFAULT")
+		} else {
+			m.add(phaseColumn, alter+"SET DEFAULT "+c.Default)
+		}
+	}
+	if ch, ok := field(cs, "Identity"); ok {
+		switch {
+		case c.Identity == "":
+			m.add(phaseColumn, alter+"DROP IDENTITY")
+		case ch.Old == "":
+			m.add(phaseColumn, alter+"ADD GENERATED "+strings.ToUpper(c.Identity)+" AS IDENTITY")
+		default:
+			m.add(phaseColumn, alter+"SET GENERATED "+strings.ToUpper(c.Identity))
+		}
+	}
+	if _, ok := field(cs, "NotNull"); ok && c.Identity == "" {
+		if c.NotNull {
+			m.add(phaseColumn, alter+"SET NOT NULL")
+		} else {
+			m.add(phaseColumn, alter+"DROP NOT NULL")
+		}
+	}
+	if _, ok := field(cs, "Comment"); ok {
+		m.add(phaseComment, commentOn("COLUMN", t+"."+col, c.Comment))
+	}
+}
+
+// sameChanges is true if both lists add or remove the object, or change
+// the same fields to the same values.
+func sameChanges(a, b []Change) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Kind != b[i].Kind || a[i].Field != b[i].Field {
+			return false
+		}
+		if a[i].Kind == Changed && !reflect.DeepEqual(a[i].New, b[i].New) {
+			return false
+		}
+	}
+	return true
+}
+
+// fromParent is true if f is true for a parent of the table (by inheritance
+// or partitioning, in this schema), either before or after the migration.
+// Changes to a parent also apply to its children.
+func (m *migrator) fromParent(table string, f func(parent string) bool) bool {
+	for _, s := range []*Schema{m.from, m.to} {
+		r := s.Relations[table]
+		for _, p := range append(r.Inherits, r.PartitionOf) {
+			if p.Name != "" && (p.Schema == "" || p.Schema == s.Name) && f(p.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// planIndexes drops and creates indexes. Indexes of primary key, unique, and
+// exclusion constraints are handled by the constraint, and partitions get
+// their indexes from the partitioned table.
+func (m *migrator) planIndexes() {
+	for _, n := range keys(m.from.Indexes, m.to.Indexes) {
+		cs := m.changes[objectKey{ObjectIndex, indexTable(m.from.Indexes[n], m.to.Indexes[n]), n}]
+		old, oldOK := m.from.Indexes[n]
+		i, ok := m.to.Indexes[n]
+		recreate := kind(cs) == Changed && !onlyFields(cs, "Comment", "Clustered", "ReplicaIdentity")
+		if oldOK && old.Constraint == "" && old.PartitionOf.Name == "" &&
+			!m.dropped[old.Table] && (kind(cs) == Removed || recreate) {
+			m.add(phaseDropIndex, "DROP INDEX "+m.name(n))
+		}
+		if !ok {
+			continue
+		}
+		create := kind(cs) == Added || recreate || m.created[i.Table]
+		if i.Constraint != "" {
+			create = m.constraintCreated(i.Table, i.Constraint)
+		}
+		if create && i.Constraint == "" && i.PartitionOf.Name == "" {
+			m.addFor(i.Table, phaseIndex, m.createIndex(n, i))
+		}
+		if create {
+			old = Index{}
+		}
+		t := m.name(i.Table)
+		if old.Clustered != i.Clustered {
+			if i.Clustered {
+				m.addFor(i.Table, phaseIndexOptions, fmt.Sprintf("ALTER TABLE %s CLUSTER ON %s", t, quoteIdent(n)))
+			} else if !create {
+				m.addFor(i.Table, phaseIndexOptions, fmt.Sprintf("ALTER TABLE %s SET WITHOUT CLUSTER", t))
+			}
+		}
+		if old.ReplicaIdentity != i.ReplicaIdentity {
+			if i.ReplicaIdentity {
+				m.addFor(i.Table, phaseIndexOptions, fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY USING INDEX %s", t, quoteIdent(n)))
+			} else if !create {
+				m.addFor(i.Table, phaseIndexOptions, fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY DEFAULT", t))
+			}
+		}
+		if old.Comment != i.Comment {
+			m.addFor(i.Table, phaseComment, commentOn("INDEX", m.name(n), i.Comment))
+		}
+	}
+}
+
+func indexTable(a, b Index) string {
+	if b.Table != "" {
+		return b.Table
+	}
+	return a.Table
+}
+
+// constraintDropped is true if a constraint needs to be dropped. Constraints
+// of dropped tables go away with the table.
+func (m *migrator) constraintDropped(table, name string) bool {
+	c, ok := m.from.Constraints[table][name]
+	if !ok || m.dropped[table] || m.inherited(m.from, table, name) {
+		return false
+	}
+	cs := m.changes[objectKey{ObjectConstraint, table, name}]
+	if kind(cs) == Removed || (kind(cs) == Changed && !onlyFields(cs, "Comment")) {
+		return true
+	}
+	// foreign keys need their referenced table and unique index
+	if c.Type == "foreign key" {
+		if (c.RefTable.Schema == m.from.Name || c.RefTable.Schema == "") && m.dropped[c.RefTable.Name] {
+			return true
+		}
+		if c.Index != "" && c.RefTable.Schema == m.from.Name {
+			if ref, ok := m.from.Indexes[c.Index]; ok {
+				if ref.Constraint != "" && (ref.Table != table || ref.Constraint != name) && m.constraintDropped(ref.Table, ref.Constraint) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// constraintCreated is true if a constraint needs to be created.
+func (m *migrator) constraintCreated(table, name string) bool {
+	if _, ok := m.to.Constraints[table][name]; !ok || m.inherited(m.to, table, name) {
+		return false
+	}
+	cs := m.changes[objectKey{ObjectConstraint, table, name}]
+	return kind(cs) == Added || m.created[table] || m.constraintDropped(table, name)
+}
+
+// inherited is true if a parent of the table has the same constraint, which
+// PostgreSQL then copies to the table.
+func (m *migrator) inherited(s *Schema, table, name string) bool {
+	c := s.Constraints[table][name]
+	return m.fromParent(table, func(parent string) bool {
+		p, ok := s.Constraints[parent][name]
+		return ok && p.Definition == c.Definition
+	})
+}
+
+func (m *migrator) planConstraints() {
+	for _, table := range keys(m.from.Constraints, m.to.Constraints) {
+		oldT, t := m.from.Constraints[table], m.to.Constraints[table]
+		for _, n := range keys(oldT, t) {
+			if m.constraintDropped(table, n) {
+				c := oldT[n]
+				phase := phaseDropConstraint
+				if c.Type == "foreign key" {
+					phase = phaseDropForeignKey
+				}
+				m.add(phase, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", m.name(table), quoteIdent(n)))
+			}
+			c, ok := t[n]
+			if !ok {
+				continue
+			}
+			target := fmt.Sprintf("%s ON %s", quoteIdent(n), m.name(table))
+			if m.constraintCreated(table, n) {
+				phase := phaseConstraint
+				if c.Type == "foreign key" {
+					phase = phaseForeignKey
+				}
+				m.addFor(table, phase, m.addConstraint(n, c))
+				if c.Comment != "" {
+					m.addFor(table, phaseComment, commentOn("CONSTRAINT", target, c.Comment))
+				}
+			} else if oldT[n].Comment != c.Comment {
+				m.addFor(table, phaseComment, commentOn("CONSTRAINT", target, c.Comment))
+			}
+		}
+	}
+}
+
+func (m *migrator) planTriggers() {
+	for _, table := range keys(m.from.Relations, m.to.Relations) {
+		oldR, r := m.from.Relations[table], m.to.Relations[table]
+		for _, n := range keys(oldR.Triggers, r.Triggers) {
+			cs := m.changes[objectKey{ObjectTrigger, table, n}]
+			old, oldOK := oldR.Triggers[n]
+			t, ok := r.Triggers[n]
+			recreate := kind(cs) == Changed && !onlyFields(cs, "Enabled", "Comment")
+			cloned := func(parent string) bool {
+				_, ok := m.to.Relations[parent].Triggers[n]
+				return ok
+			}
+			if m.fromParent(table, cloned) {
+				continue
+			}
+			if oldOK && !m.dropped[table] && (kind(cs) == Removed || recreate) {
+				m.add(phaseDropTrigger, fmt.Sprintf("DROP TRIGGER %s ON %s", quoteIdent(n), m.name(table)))
+			}
+			if !ok {
+				continue
+			}
+			create := kind(cs) == Added || recreate || m.created[table]
+			if create {
+				old = Trigger{Enabled: "origin"}
+				m.addFor(table, phaseTrigger, m.createTrigger(table, n, t))
+			}
+			if t.Enabled != "" && old.Enabled != t.Enabled {
+				m.addFor(table, phaseTrigger, m.triggerEnable(table, n, t))
+			}
+			if old.Comment != t.Comment {
+				m.addFor(table, phaseComment, commentOn("TRIGGER", quoteIdent(n)+" ON "+m.name(table), t.Comment))
+			}
+		}
+	}
+}
+
+func (m *migrator) planPolicies() {
+	for _, table := range keys(m.from.Relations, m.to.Relations) {
+		oldR, r := m.from.Relations[table], m.to.Relations[table]
+		for _, n := range keys(oldR.Policies, r.Policies) {
+			cs := m.changes[objectKey{ObjectPolicy, table, n}]
+			old, oldOK := oldR.Policies[n]
+			p, ok := r.Policies[n]
+			recreate := kind(cs) == Changed && !onlyFields(cs, "Comment")
+			if oldOK && !m.dropped[table] && (kind(cs) == Removed || recreate) {
+				m.add(phaseDropTrigger, fmt.Sprintf("DROP POLICY %s ON %s", quoteIdent(n), m.name(table)))
+			}
+			if !ok {
+				continue
+			}
+			if kind(cs) == Added || recreate || m.created[table] {
+				old = Policy{}
+				m.addFor(table, phaseTrigger, m.createPolicy(table, n, p))
+			}
+			if old.Comment != p.Comment {
+				m.addFor(table, phaseComment, commentOn("POLICY", quoteIdent(n)+" ON "+m.name(table), p.Comment))
+			}
+		}
+	}
+}
+
+func (m *migrator) planSequence(name string, cs []Change) {
+	var (
+		old, oldOK = m.from.Sequences[name]
+		s, ok      = m.to.Sequences[name]
+		seq        = m.name(name)
+	)
+	// identity sequences come and go with their column
+	if m.isIdentity(m.from, old) || m.isIdentity(m.to, s) {
+		return
+	}
+	switch {
+	case !ok:
+		if m.dropped[old.OwnedByTable] {
+			return
+		}
+		if c := m.changes[objectKey{ObjectColumn, old.OwnedByTable, old.OwnedByColumn}]; kind(c) == Removed {
+			return
+		}
+		m.addDestructive(phaseDropSequence, "DROP SEQUENCE "+seq)
+		return
+	case !oldOK:
+		m.add(phaseSequence, m.createSequence(name, s))
+		old = Sequence{}
+	default:
+		alter := ""
+		if old.Type != s.Type && s.Type != "" {
+			alter += " AS " + s.Type
+		}
+		alter += sequenceOptions(old, s)
+		if alter != "" {
+			m.add(phaseSequence, "ALTER SEQUENCE "+seq+alter)
+		}
+	}
+	if old.OwnedByTable != s.OwnedByTable || old.OwnedByColumn != s.OwnedByColumn {
+		owner := "NONE"
+		if s.OwnedByTable != "" {
+			owner = m.name(s.OwnedByTable) + "." + quoteIdent(s.OwnedByColumn)
+		}
+		m.addFor(s.OwnedByTable, phaseOwnedBy, "ALTER SEQUENCE "+seq+" OWNED BY "+owner)
+	}
+	if old.Comment != s.Comment {
+		m.add(phaseComment, commentOn("SEQUENCE", seq, s.Comment))
+	}
+}
+
+func (m *migrator) isIdentity(s *Schema, seq Sequence) bool {
+	return seq.OwnedByTable != "" && s.Relations[seq.OwnedByTable].Columns[seq.OwnedByColumn].Identity != ""
+}
+
+func (m *migrator) planType(name string, cs []Change) {
+	var (
+		old, oldOK = m.from.Types[name]
+		t, ok      = m.to.Types[name]
+		ty         = m.name(name)
+		drop       = "DROP " + typeKind(old) + " " + ty
+		requires   string
+	)
+	if !ok {
+		m.addDestructive(phaseDropType, drop)
+		return
+	}
+	if oldOK {
+		switch {
+		case onlyFields(cs, "Comment", "Owner", "Privileges"):
+		case old.Type == "enum" && t.Type == "enum" && onlyFields(cs, "Labels", "Comment", "Owner", "Privileges") && isSubsequence(old.Labels, t.Labels):
+			for i, l := range t.Labels {
+				if hasString(old.Labels, l) {
+					continue
+				}
+				where := ""
+				if i > 0 {
+					where = " AFTER " + quoteLiteral(t.Labels[i-1])
+				} else if len(t.Labels) > 1 {
+					where = " BEFORE " + quoteLiteral(t.Labels[1])
+				}
+				m.add(phaseType, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s%s", ty, quoteLiteral(l), where))
+			}
+		case old.Type == "domain" && t.Type == "domain" && onlyFields(cs, "Default", "NotNull", "Constraints", "Comment", "Owner", "Privileges"):
+			m.alterDomain(ty, old, t)
+		case old.Type == "composite" && t.Type == "composite" && onlyFields(cs, "Attributes", "Comment", "Owner", "Privileges"):
+			m.alterComposite(ty, old, t)
+		default:
+			// can't be changed in place
+			m.addDestructive(phaseDropType, drop)
+			requires = drop
+			oldOK = false
+		}
+	}
+	if !oldOK {
+		m.addRequires(phaseType, m.createType(name, t), requires)
+		old = Type{}
+	}
+	if old.Comment != t.Comment {
+		m.addRequires(phaseComment, commentOn(typeKind(t), ty, t.Comment), requires)
+	}
+}
+
+func (m *migrator) alterDomain(ty string, old, t Type) {
+	alter := "ALTER DOMAIN " + ty + " "
+	if old.Default != t.Default {
+		if t.Default == "" {
+			m.add(phaseType, alter+"DROP DEFAULT")
+		} else {
+			m.add(phaseType, alter+"SET DEFAULT "+t.Default)
+		}
+	}
+	if old.NotNull != t.NotNull {
+		if t.NotNull {
+			m.add(phaseType, alter+"SET NOT NULL")
+		} else {
+			m.add(phaseType, alter+"DROP NOT NULL")
+		}
+	}
+	for _, n := range keys(old.Constraints, t.Constraints) {
+		oc, oldOK := old.Constraints[n]
+		c, ok := t.Constraints[n]
+		if oldOK && ok && oc.Definition == c.Definition {
+			continue
+		}
+		if oldOK {
+			m.add(phaseType, alter+"DROP CONSTRAINT "+quoteIdent(n))
+		}
+		if ok {
+			m.add(phaseType, alter+"ADD CONSTRAINT "+quoteIdent(n)+" "+c.Definition)
+		}
+	}
+}
+
+func (m *migrator) alterComposite(ty string, old, t Type) {
+	alter := "ALTER TYPE " + ty + " "
+	for _, n := range keys(old.Attributes, t.Attributes) {
+		oa, oldOK := old.Attributes[n]
+		a, ok := t.Attributes[n]
+		switch {
+		case !ok:
+			m.addDestructive(phaseType, alter+"DROP ATTRIBUTE "+quoteIdent(n))
+		case !oldOK:
+			m.add(phaseType, alter+"ADD ATTRIBUTE "+quoteIdent(n)+" "+a.FullType)
+		case oa.FullType != a.FullType:
+			m.addDestructive(phaseType, alter+"ALTER ATTRIBUTE "+quoteIdent(n)+" TYPE "+a.FullType)
+		}
+	}
+}
+
+func (m *migrator) planFunction(sig string, cs []Change) {
+	var (
+		old, oldOK = m.from.Functions[sig]
+		f, ok      = m.to.Functions[sig]
+	)
+	if (oldOK && old.Kind == "aggregate") || (ok && f.Kind == "aggregate") {
+		return
+	}
+	recreate := kind(cs) == Changed && !onlyFields(cs, "Comment", "Owner", "Privileges")
+	if oldOK && (!ok || (recreate && !onlyFields(cs, "Language", "Volatility", "Strict", "Parallel", "Leakproof", "SecurityDefiner", "Cost", "Rows", "Config", "Src", "Comment", "Owner", "Privileges"))) {
+		// CREATE OR REPLACE can't change the result or the arguments
+		m.add(phaseDropFunction, "DROP "+functionKind(old)+" "+m.functionName(old))
+	}
+	if !ok {
+		return
+	}
+	if !oldOK || recreate {
+		m.add(phaseFunction, m.createFunction(f))
+		if !oldOK {
+			old = Function{}
+		}
+	}
+	if old.Comment != f.Comment {
+		m.add(phaseComment, commentOn(functionKind(f), m.functionName(f), f.Comment))
+	}
+}
+
+func (m *migrator) planEventTrigger(name string, cs []Change) {
+	var (
+		old, oldOK = m.from.EventTriggers[name]
+		e, ok      = m.to.EventTriggers[name]
+		et         = quoteIdent(name)
+	)
+	recreate := kind(cs) == Changed && !onlyFields(cs, "Enabled", "Comment")
+	if oldOK && (!ok || recreate) {
+		m.add(phaseDropEventTrigger, "DROP EVENT TRIGGER "+et)
+	}
+	if !ok {
+		return
+	}
+	if !oldOK || recreate {
+		m.add(phaseEventTrigger, createEventTrigger(name, e))
+		old = EventTrigger{Enabled: "origin"}
+	}
+	if e.Enabled != "" && old.Enabled != e.Enabled {
+		m.add(phaseEventTrigger, "ALTER EVENT TRIGGER "+et+" "+enabledClause(e.Enabled))
+	}
+	if old.Comment != e.Comment {
+		m.add(phaseComment, commentOn("EVENT TRIGGER", et, e.Comment))
+	}
+}
+
+// relationDeps gives the relations in the same schema a relation depends on:
+// the parents of a table, and the relations a view uses.
+func relationDeps(s *Schema) func(string) []string {
+	return func(n string) []string {
+		r := s.Relations[n]
+		var deps []string
+		for _, p := range append(r.Inherits, r.PartitionOf) {
+			if p.Name != "" && (p.Schema == "" || p.Schema == s.Name) {
+				deps = append(deps, p.Name)
+			}
+		}
+		if isView(r) {
+			uses := mentions(r.Definition)
+			for _, o := range keys(s.Relations, s.Relations) {
+				if o != n && uses(o) {
+					deps = append(deps, o)
+				}
+			}
+		}
+		return deps
+	}
+}
+
+// dependencyOrder orders names alphabetically, but with every name after its
+// dependencies. Dependencies which are not in names are ignored, and cycles
+// are broken arbitrarily.
+func dependencyOrder(names []string, deps func(string) []string) []string {
+	sort.Strings(names)
+	var (
+		res  []string
+		in   = map[string]bool{}
+		seen = map[string]bool{}
+	)
+	for _, n := range names {
+		in[n] = true
+	}
+	var visit func(string)
+	visit = func(n string) {
+		if seen[n] || !in[n] {
+			return
+		}
+		seen[n] = true
+		for _, d := range deps(n) {
+			visit(d)
+		}
+		res = append(res, n)
+	}
+	for _, n := range names {
+		visit(n)
+	}
+	return res
+}
+
+// mentions gives a func which is true if an identifier appears in a view
+// definition. This can give false positives, which only cost an unneeded
+// DROP and CREATE.
+func mentions(def string) func(name string) bool {
+	stmts, err := lex(def)
+	if err != nil {
+		return func(string) bool { return true }
+	}
+	ids := map[string]bool{}
+	for _, ts := range stmts {
+		for _, t := range ts {
+			if t.typ == tokIdent || t.typ == tokQuoted {
+				ids[t.text] = true
+			}
+		}
+	}
+	return func(name string) bool { return ids[name] }
+}
+
+func setNames(set map[string]bool) []string {
+	var names []string
+	for n, ok := range set {
+		if ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasQName(list []QName, q QName) bool {
+	for _, l := range list {
+		if l == q {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubsequence is true if all of a is in b, in the same order.
+func isSubsequence(a, b []string) bool {
+	i := 0
+	for _, s := range b {
+		if i < len(a) && a[i] == s {
+			i++
+		}
+	}
+	return i == len(a)
+}
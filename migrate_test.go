@@ -0,0 +1,428 @@
+package schemaspy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	users := Relation{
+		Type: "table",
+		Columns: map[string]Column{
+			"id":   {FullType: "integer", NotNull: true, Default: "nextval('app.users_id_seq'::regclass)", Position: 1},
+			"name": {FullType: "text", Position: 2},
+		},
+		Constraints: []string{"users_pkey"},
+	}
+	live := &Schema{
+		Name: "app",
+		Relations: map[string]Relation{
+			"users": users,
+			"names": {
+				Type:       "view",
+				Definition: " SELECT name\n   FROM app.users;",
+			},
+			"old": {
+				Type:    "table",
+				Columns: map[string]Column{"a": {FullType: "integer", Position: 1}},
+			},
+		},
+		Sequences: map[string]Sequence{
+			"users_id_seq": {Type: "integer", IncrementBy: 1, MinValue: 1, MaxValue: 2147483647, Start: 1, Cache: 1, OwnedByTable: "users", OwnedByColumn: "id"},
+		},
+		Constraints: map[string]map[string]Constraint{
+			"users": {
+				"users_pkey": {Type: "primary key", Table: "users", Columns: []string{"id"}, Index: "users_pkey", Validated: true, Definition: "PRIMARY KEY (id)"},
+			},
+		},
+		Indexes: map[string]Index{
+			"users_pkey": {Table: "users", Type: "btree", Unique: true, Primary: true, Constraint: "users_pkey", Columns: []string{"id"}},
+		},
+		Types: map[string]Type{
+			"mood": {Type: "enum", Labels: []string{"sad", "happy"}},
+		},
+	}
+
+	wantUsers := users
+	wantUsers.Columns = map[string]Column{
+		"id":    users.Columns["id"],
+		"name":  {FullType: "character varying(100)", NotNull: true, Position: 2},
+		"email": {FullType: "text", Position: 3, Comment: "primary email"},
+	}
+	wanted := &Schema{
+		Name: "app",
+		Relations: map[string]Relation{
+			"users": wantUsers,
+			"names": live.Relations["names"],
+			"posts": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id":      {FullType: "bigint", NotNull: true, Identity: "always", Position: 1},
+					"user_id": {FullType: "integer", NotNull: true, Position: 2},
+					"mood":    {FullType: "app.mood", Position: 3},
+				},
+				Constraints: []string{"posts_user_id_fkey"},
+				Indexes:     []string{"posts_user_id"},
+			},
+		},
+		Sequences: map[string]Sequence{
+			"users_id_seq": live.Sequences["users_id_seq"],
+			"posts_id_seq": {Type: "bigint", IncrementBy: 1, OwnedByTable: "posts", OwnedByColumn: "id"},
+		},
+		Constraints: map[string]map[string]Constraint{
+			"users": live.Constraints["users"],
+			"posts": {
+				"posts_user_id_fkey": {Type: "foreign key", Table: "posts", Columns: []string{"user_id"}, RefTable: QName{"app", "users"}, RefColumns: []string{"id"}, OnDelete: "cascade", Validated: true},
+			},
+		},
+		Indexes: map[string]Index{
+			"users_pkey":    live.Indexes["users_pkey"],
+			"posts_user_id": {Table: "posts", Type: "btree", Keys: []IndexKey{{Column: "user_id"}}},
+		},
+		Types: map[string]Type{
+			"mood": {Type: "enum", Labels: []string{"sad", "ok", "happy"}},
+		},
+	}
+
+	m := Migrate(live, wanted)
+	if have, want := m, (Migration{
+		{SQL: "DROP VIEW app.names"},
+		{SQL: "DROP TABLE app.old", Destructive: true},
+		{SQL: "ALTER TYPE app.mood ADD VALUE 'ok' AFTER 'sad'"},
+		{SQL: "CREATE TABLE app.posts (\n    id bigint GENERATED ALWAYS AS IDENTITY,\n    user_id integer NOT NULL,\n    mood app.mood\n)"},
+		{SQL: "ALTER TABLE app.users ADD COLUMN email text"},
+		{SQL: "ALTER TABLE app.users ALTER COLUMN name TYPE character varying(100)", Destructive: true},
+		{SQL: "ALTER TABLE app.users ALTER COLUMN name SET NOT NULL"},
+		{SQL: "CREATE VIEW app.names AS\nSELECT name\n   FROM app.users"},
+		{SQL: "CREATE INDEX posts_user_id ON app.posts USING btree (user_id)"},
+		{SQL: "ALTER TABLE app.posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES app.users(id) ON DELETE CASCADE"},
+		{SQL: "COMMENT ON COLUMN app.users.email IS 'primary email'"},
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := len(m.Safe()), len(m)-2; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := m.Destructive().String(), "-- destructive\nDROP TABLE app.old;\n-- destructive\nALTER TABLE app.users ALTER COLUMN name TYPE character varying(100);\n"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := len(Migrate(live, live)), 0; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestMigrateCreate(t *testing.T) {
+	// new tables need their sequences, and parents, first
+	s := &Schema{
+		Name: "app",
+		Relations: map[string]Relation{
+			"b_root": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id": {FullType: "integer", NotNull: true, Default: "nextval('app.b_root_id_seq'::regclass)", Position: 1},
+				},
+			},
+			"a_child": {
+				Type: "table",
+				Columns: map[string]Column{
+					"id": {FullType: "integer", NotNull: true, Default: "nextval('app.b_root_id_seq'::regclass)", Position: 1},
+				},
+				Inherits: []QName{{"app", "b_root"}},
+			},
+		},
+		Sequences: map[string]Sequence{
+			"b_root_id_seq": {Type: "integer", OwnedByTable: "b_root", OwnedByColumn: "id"},
+		},
+	}
+	var have []string
+	for _, st := range Migrate(nil, s) {
+		have = append(have, st.SQL)
+	}
+	if want := []string{
+		"CREATE SEQUENCE app.b_root_id_seq AS integer",
+		"CREATE TABLE app.b_root (\n    id integer DEFAULT nextval('app.b_root_id_seq'::regclass) NOT NULL\n)",
+		"CREATE TABLE app.a_child (\n    id integer DEFAULT nextval('app.b_root_id_seq'::regclass) NOT NULL\n) INHERITS (app.b_root)",
+		"ALTER SEQUENCE app.b_root_id_seq OWNED BY app.b_root.id",
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	var dropped []string
+	for _, st := range Migrate(s, nil) {
+		dropped = append(dropped, st.SQL)
+	}
+	if want := []string{
+		"DROP TABLE app.a_child",
+		"DROP TABLE app.b_root",
+	}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("have %#v, want %#v", dropped, want)
+	}
+}
+
+func TestMigrateConstraintNames(t *testing.T) {
+	// constraint names are only unique per table
+	live, err := ParseDDL("public", `
+CREATE TABLE orders (amount int CONSTRAINT positive CHECK (amount > 0));
+CREATE TABLE refunds (amount int CONSTRAINT positive CHECK (amount > 0));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(live.Constraints), 2; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	if have, want := live.Constraints["refunds"]["positive"].Table, "refunds"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	wanted, err := ParseDDL("public", `
+CREATE TABLE orders (amount int CONSTRAINT positive CHECK (amount > 0));
+CREATE TABLE refunds (amount int CONSTRAINT positive CHECK (amount < 0));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, c := range Diff(live, wanted) {
+		have = append(have, c.String())
+	}
+	if want := []string{
+		`changed constraint "refunds"."positive" Definition: "CHECK ((amount > 0))" -> "CHECK ((amount < 0))"`,
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	have = nil
+	for _, st := range Migrate(live, wanted) {
+		have = append(have, st.SQL)
+	}
+	if want := []string{
+		"ALTER TABLE public.refunds DROP CONSTRAINT positive",
+		"ALTER TABLE public.refunds ADD CONSTRAINT positive CHECK ((amount < 0))",
+	}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestParens(t *testing.T) {
+	for s, want := range map[string]string{
+		"a > 0":         "(a > 0)",
+		"(a > 0)":       "(a > 0)",
+		"(a) > (0)":     "((a) > (0))",
+		"((a > 0))":     "((a > 0))",
+		"lower(name)":   "(lower(name))",
+		"(a = 1) OR b":  "((a = 1) OR b)",
+		"(x) AND (y)":   "((x) AND (y))",
+		"(f(a), g(b))":  "(f(a), g(b))",
+		"":              "()",
+		"(unbalanced":   "((unbalanced)",
+		"count(*) > 10": "(count(*) > 10)",
+	} {
+		if have := parens(s); have != want {
+			t.Errorf("%q: have %#v, want %#v", s, have, want)
+		}
+	}
+}
+
+func TestMigrateChanges(t *testing.T) {
+	for _, c := range []struct {
+		name, live, wanted, want string
+	}{
+		{
+			name: "trigger",
+			live: `CREATE TABLE t (id int);
+CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION f();
+CREATE TRIGGER gone AFTER INSERT ON t FOR EACH ROW EXECUTE FUNCTION f();`,
+			wanted: `CREATE TABLE t (id int);
+CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE TRIGGER tr BEFORE UPDATE ON t FOR EACH ROW EXECUTE FUNCTION f();
+ALTER TABLE t DISABLE TRIGGER tr;
+COMMENT ON TRIGGER tr ON t IS 'hi';`,
+			want: `DROP TRIGGER gone ON public.t;
+DROP TRIGGER tr ON public.t;
+CREATE TRIGGER tr BEFORE UPDATE ON public.t FOR EACH ROW EXECUTE FUNCTION public.f();
+ALTER TABLE public.t DISABLE TRIGGER tr;
+COMMENT ON TRIGGER tr ON public.t IS 'hi';
+`,
+		},
+		{
+			name: "policy",
+			live: `CREATE TABLE t (id int);
+CREATE POLICY p ON t USING (id > 0);`,
+			wanted: `CREATE TABLE t (id int);
+ALTER TABLE t ENABLE ROW LEVEL SECURITY;
+CREATE POLICY p ON t USING (id > 1);
+CREATE POLICY q ON t FOR SELECT USING (true);`,
+			want: `DROP POLICY p ON public.t;
+ALTER TABLE public.t ENABLE ROW LEVEL SECURITY;
+CREATE POLICY p ON public.t TO PUBLIC USING (id > 1);
+CREATE POLICY q ON public.t FOR SELECT TO PUBLIC USING (true);
+`,
+		},
+		{
+			name: "enum and domain",
+			live: `CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE DOMAIN pos AS int CHECK (VALUE > 0);`,
+			wanted: `CREATE TYPE mood AS ENUM ('meh', 'sad', 'happy', 'glad');
+CREATE DOMAIN pos AS int DEFAULT 1 NOT NULL CHECK (VALUE > 0) CHECK (VALUE < 100);`,
+			want: `ALTER TYPE public.mood ADD VALUE 'meh' BEFORE 'sad';
+ALTER TYPE public.mood ADD VALUE 'glad' AFTER 'happy';
+ALTER DOMAIN public.pos SET DEFAULT 1;
+ALTER DOMAIN public.pos SET NOT NULL;
+ALTER DOMAIN public.pos ADD CONSTRAINT pos_check1 CHECK ((VALUE < 100));
+`,
+		},
+		{
+			name: "enum reordered, domain removed",
+			live: `CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE DOMAIN pos AS int;`,
+			wanted: `CREATE TYPE mood AS ENUM ('happy', 'sad');`,
+			want: `-- destructive
+DROP TYPE public.mood;
+-- destructive
+DROP DOMAIN public.pos;
+CREATE TYPE public.mood AS ENUM ('happy', 'sad');
+`,
+		},
+		{
+			name:   "sequence",
+			live:   `CREATE SEQUENCE s; CREATE SEQUENCE gone;`,
+			wanted: `CREATE SEQUENCE s INCREMENT BY 2 MAXVALUE 100 CYCLE; CREATE SEQUENCE n AS int;`,
+			want: `CREATE SEQUENCE public.n AS integer INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START WITH 1 CACHE 1;
+ALTER SEQUENCE public.s INCREMENT BY 2 MAXVALUE 100 CYCLE;
+-- destructive
+DROP SEQUENCE public.gone;
+`,
+		},
+		{
+			name:   "serial to int",
+			live:   `CREATE TABLE a (id serial);`,
+			wanted: `CREATE TABLE a (id int NOT NULL);`,
+			want: `ALTER TABLE public.a ALTER COLUMN id DROP DEFAULT;
+-- destructive
+DROP SEQUENCE public.a_id_seq;
+`,
+		},
+		{
+			name: "partitions",
+			live: `CREATE TABLE e (at date) PARTITION BY RANGE (at);
+CREATE TABLE e1 PARTITION OF e FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');
+CREATE TABLE e2 (at date);
+CREATE TABLE e3 PARTITION OF e FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');`,
+			wanted: `CREATE TABLE e (at date) PARTITION BY RANGE (at);
+CREATE TABLE e1 PARTITION OF e FOR VALUES FROM ('2020-01-01') TO ('2022-01-01');
+CREATE TABLE e2 PARTITION OF e FOR VALUES FROM ('2022-01-01') TO ('2023-01-01');
+CREATE TABLE e3 (at date);`,
+			want: `ALTER TABLE public.e DETACH PARTITION public.e1;
+ALTER TABLE public.e DETACH PARTITION public.e3;
+ALTER TABLE public.e ATTACH PARTITION public.e1 FOR VALUES FROM ('2020-01-01') TO ('2022-01-01');
+ALTER TABLE public.e ATTACH PARTITION public.e2 FOR VALUES FROM ('2022-01-01') TO ('2023-01-01');
+`,
+		},
+		{
+			name: "inheritance",
+			live: `CREATE TABLE a (id int);
+CREATE TABLE b (id int);
+CREATE TABLE c (id int) INHERITS (a);`,
+			wanted: `CREATE TABLE a (id int);
+CREATE TABLE b (id int);
+CREATE TABLE c (id int) INHERITS (b);`,
+			want: `ALTER TABLE public.c NO INHERIT public.a;
+ALTER TABLE public.c INHERIT public.b;
+`,
+		},
+	} {
+		live, err := ParseDDL("public", c.live)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		wanted, err := ParseDDL("public", c.wanted)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if have := Migrate(live, wanted).String(); have != c.want {
+			t.Errorf("%s: have:\n%s\nwant:\n%s", c.name, have, c.want)
+		}
+	}
+}
+
+func TestMigrateSafe(t *testing.T) {
+	// a table which is created again brings everything on it along
+	live, err := ParseDDL("public", `
+CREATE TABLE e (at date, id int) PARTITION BY RANGE (at);
+CREATE TABLE e1 PARTITION OF e FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');
+CREATE TABLE o (id int);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted, err := ParseDDL("public", `
+CREATE TABLE e (at date, id int) PARTITION BY LIST (id);
+CREATE TABLE e1 PARTITION OF e FOR VALUES IN (1);
+CREATE INDEX e_id ON e (id);
+COMMENT ON TABLE e IS 'events';
+CREATE POLICY p ON e USING (true);
+CREATE SEQUENCE s OWNED BY e.id;
+CREATE TABLE o (id int, CONSTRAINT o_id CHECK (id > 0));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Migrate(live, wanted)
+	if have, want := len(m), 10; have != want {
+		t.Errorf("have %#v, want %#v:\n%s", have, want, m)
+	}
+	if have, want := m.Safe().String(), `CREATE SEQUENCE public.s AS bigint INCREMENT BY 1 MINVALUE 1 MAXVALUE 9223372036854775807 START WITH 1 CACHE 1;
+ALTER TABLE public.o ADD CONSTRAINT o_id CHECK ((id > 0));
+`; have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+func TestMigrateSchemaNames(t *testing.T) {
+	// everything is created in the schema of live
+	live, err := ParseDDL("app", `
+CREATE SCHEMA app;
+SET search_path TO app;
+CREATE TABLE users (id int);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted, err := ParseDDL("app2", `
+CREATE SCHEMA app2;
+SET search_path TO app2;
+CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE TABLE users (id serial PRIMARY KEY, m mood);
+CREATE TABLE posts (user_id int REFERENCES users (id));
+CREATE INDEX posts_user ON posts (user_id);
+CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE TRIGGER touch BEFORE INSERT ON users FOR EACH ROW EXECUTE FUNCTION touch();
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as Describe() would give them
+	idx := wanted.Indexes["posts_user"]
+	idx.Definition = "CREATE INDEX posts_user ON app2.posts USING btree (user_id)"
+	wanted.Indexes["posts_user"] = idx
+
+	m := Migrate(live, wanted).String()
+	if strings.Contains(m, "app2") {
+		t.Errorf("have:\n%s", m)
+	}
+	for _, want := range []string{
+		"CREATE INDEX posts_user ON app.posts USING btree (user_id);\n",
+		"REFERENCES app.users(id);\n",
+		"SET DEFAULT nextval('app.users_id_seq'::regclass);\n",
+		"ADD COLUMN m app.mood;\n",
+		"EXECUTE FUNCTION app.touch();\n",
+	} {
+		if !strings.Contains(m, want) {
+			t.Errorf("no %q in:\n%s", want, m)
+		}
+	}
+}
@@ -0,0 +1,3648 @@
+package schemaspy
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseDDL builds a Schema from SQL statements, without a database. Leave
+// schema empty for the public schema. It's the offline version of
+// Describe(): the result is what Describe() would give after running the
+// statements on an empty database, so a Schema from a file can be compared
+// with Diff() to a live one, or to another file.
+//
+// Only DDL is understood: CREATE and DROP of tables, indexes, views,
+// materialized views, sequences, functions, procedures, types, domains,
+// triggers, policies, and event triggers; ALTER TABLE, SEQUENCE, TYPE,
+// DOMAIN, and EVENT TRIGGER; COMMENT ON, CLUSTER, and SET search_path. That
+// covers pg_dump --schema-only output, and what WriteDDL() writes. Data
+// statements, GRANT and REVOKE, and OWNER TO are skipped. Anything else is an
+// error, which starts with the line number.
+//
+// Unqualified names are in the schema from the last SET search_path, which
+// starts as schema. With an empty search_path, as pg_dump sets it, names must
+// be qualified. Objects in other schemas are skipped, other than that they
+// can be children or partitions of our tables.
+//
+// There are differences with what Describe() gives:
+//   - expressions, such as defaults, check constraints, view queries, and
+//     index predicates are kept as written, and not in the form PostgreSQL
+//     prints them. The Definition fields are made up from the parts,
+//     and are empty for functions and triggers.
+//   - views have no columns
+//   - Owner, Privileges, and the state of sequences are not set
+//   - constraints are not copied to child tables and partitions, indexes
+//     are. Exclusion constraints don't get an index.
+func ParseDDL(schema, sql string) (*Schema, error) {
+	p := newDDLParser(schema)
+	if err := p.parse(sql); err != nil {
+		return nil, err
+	}
+	return p.finish(), nil
+}
+
+// ParseDDLFiles is ParseDDL() on the files, in order. Errors have the
+// filename.
+func ParseDDLFiles(schema string, files ...string) (*Schema, error) {
+	p := newDDLParser(schema)
+	for _, f := range files {
+		sql, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parse(string(sql)); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	return p.finish(), nil
+}
+
+type ddlParser struct {
+	s *Schema
+	// search is where unqualified names live, as set by SET search_path
+	search string
+	// attNums has the last used AttNum of every relation
+	attNums map[string]int
+	// others are the tables in other schemas which inherit from, or are a
+	// partition of, one of our tables
+	others map[QName]Relation
+}
+
+func newDDLParser(schema string) *ddlParser {
+	if schema == "" {
+		schema = "public"
+	}
+	p := &ddlParser{
+		search: schema,
+	}
+	p.reset(schema)
+	return p
+}
+
+// reset starts over with an empty schema
+func (p *ddlParser) reset(schema string) {
+	p.s = &Schema{
+		Name:          schema,
+		Relations:     map[string]Relation{},
+		Indexes:       map[string]Index{},
+		Sequences:     map[string]Sequence{},
+		Constraints:   map[string]map[string]Constraint{},
+		Types:         map[string]Type{},
+		EventTriggers: map[string]EventTrigger{},
+		Functions:     map[string]Function{},
+	}
+	p.attNums = map[string]int{}
+	p.others = map[QName]Relation{}
+}
+
+func (p *ddlParser) parse(sql string) error {
+	stmts, err := lex(sql)
+	if err != nil {
+		return err
+	}
+	for _, ts := range stmts {
+		c := &cursor{ts: ts}
+		if err := p.statement(c); err != nil {
+			return err
+		}
+		if !c.eof() {
+			return c.errorf("unexpected input")
+		}
+	}
+	return nil
+}
+
+// ignoredStatements don't change the structure of a schema
+var ignoredStatements = set(
+	"analyse", "analyze", "begin", "checkpoint", "commit", "delete",
+	"discard", "do", "end", "grant", "insert", "listen", "lock", "notify",
+	"refresh", "reindex", "release", "reset", "revoke", "rollback",
+	"savepoint", "security", "select", "start", "truncate", "update",
+	"vacuum", "values", "with",
+)
+
+func (p *ddlParser) statement(c *cursor) error {
+	switch {
+	case c.accept("create"):
+		return p.create(c)
+	case c.accept("alter"):
+		return p.alter(c)
+	case c.accept("drop"):
+		return p.drop(c)
+	case c.accept("comment", "on"):
+		return p.comment(c)
+	case c.accept("set"):
+		return p.set(c)
+	case c.accept("cluster"):
+		return p.cluster(c)
+	case c.peek().typ == tokIdent && ignoredStatements[c.peek().text]:
+		c.rest()
+		return nil
+	default:
+		return c.errorf("unsupported statement")
+	}
+}
+
+func (p *ddlParser) create(c *cursor) error {
+	replace := c.accept("or", "replace")
+	switch {
+	case c.accept("schema"):
+		c.accept("if", "not", "exists")
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+		if c.accept("authorization") {
+			_, err := c.ident()
+			return err
+		}
+		return nil
+	case c.accept("table"), c.accept("unlogged", "table"):
+		return p.createTable(c)
+	case c.accept("unique", "index"):
+		return p.createIndex(c, true)
+	case c.accept("index"):
+		return p.createIndex(c, false)
+	case c.accept("view"):
+		return p.createView(c, "view")
+	case c.accept("materialized", "view"):
+		return p.createView(c, "materialized view")
+	case c.accept("sequence"):
+		return p.createSequence(c)
+	case c.accept("function"):
+		return p.createFunction(c, "function", replace)
+	case c.accept("procedure"):
+		return p.createFunction(c, "procedure", replace)
+	case c.accept("type"):
+		return p.createType(c)
+	case c.accept("domain"):
+		return p.createDomain(c)
+	case c.accept("trigger"):
+		return p.createTrigger(c, false)
+	case c.accept("constraint", "trigger"):
+		return p.createTrigger(c, true)
+	case c.accept("policy"):
+		return p.createPolicy(c)
+	case c.accept("event", "trigger"):
+		return p.createEventTrigger(c)
+	case c.accept("extension"):
+		c.rest()
+		return nil
+	default:
+		return c.errorf("unsupported statement")
+	}
+}
+
+func (p *ddlParser) alter(c *cursor) error {
+	if n := len(c.ts); n >= 3 && c.ts[n-3].is("owner") && c.ts[n-2].is("to") {
+		c.rest()
+		return nil
+	}
+	switch {
+	case c.accept("table"):
+		return p.alterTable(c)
+	case c.accept("index"):
+		return p.alterIndex(c)
+	case c.accept("sequence"):
+		return p.alterSequence(c)
+	case c.accept("type"):
+		return p.alterType(c)
+	case c.accept("domain"):
+		return p.alterDomain(c)
+	case c.accept("event", "trigger"):
+		return p.alterEventTrigger(c)
+	case c.accept("default", "privileges"):
+		c.rest()
+		return nil
+	default:
+		return c.errorf("unsupported statement")
+	}
+}
+
+// set handles SET search_path, and ignores all other settings.
+func (p *ddlParser) set(c *cursor) error {
+	if !c.accept("session") {
+		c.accept("local")
+	}
+	if !c.accept("search_path") {
+		c.rest()
+		return nil
+	}
+	if !c.accept("to") {
+		if err := c.expect("="); err != nil {
+			return err
+		}
+	}
+	p.search = ""
+	list, err := splitTokens(c.rest())
+	if err != nil {
+		return err
+	}
+	for _, v := range list {
+		if len(v) != 1 {
+			continue
+		}
+		if s := v[0].text; s != "" && s != "pg_catalog" && s != "$user" && s != "pg_temp" {
+			p.search = s
+			break
+		}
+	}
+	return nil
+}
+
+// qname reads a name. Unqualified names need a schema in the search_path.
+func (p *ddlParser) qname(c *cursor) (QName, error) {
+	q, err := c.qname()
+	if err == nil && q.Schema == "" && p.search == "" {
+		err = c.errorf("no schema has been selected for %q", q.Name)
+	}
+	return q, err
+}
+
+// local gives the name of q if it's in our schema.
+func (p *ddlParser) local(q QName) (string, bool) {
+	return q.Name, p.qualify(q).Schema == p.s.Name
+}
+
+// qualify gives q with the schema unqualified names are in.
+func (p *ddlParser) qualify(q QName) QName {
+	if q.Schema == "" {
+		q.Schema = p.search
+	}
+	return q
+}
+
+// relation finds one of our relations by its name in the SQL.
+func (p *ddlParser) relation(c *cursor, q QName) (string, Relation, bool, error) {
+	name, ok := p.local(q)
+	if !ok {
+		return "", Relation{}, false, nil
+	}
+	r, ok := p.s.Relations[name]
+	if !ok {
+		return "", Relation{}, false, c.errorf("relation %q does not exist", name)
+	}
+	return name, r, true, nil
+}
+
+// children gives our tables which inherit from, or are a partition of,
+// table.
+func (p *ddlParser) children(table string) []string {
+	parent := QName{p.s.Name, table}
+	var res []string
+	for n, r := range p.s.Relations {
+		if r.PartitionOf == parent || hasQName(r.Inherits, parent) {
+			res = append(res, n)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// uniqueName makes a name for an index, constraint, or sequence the way
+// PostgreSQL does, such as "users_name_key", or "users_name_key1" if that
+// one is taken.
+func (p *ddlParser) uniqueName(table string, columns []string, suffix string) string {
+	base := strings.Join(append([]string{table}, columns...), "_")
+	name := base + "_" + suffix
+	for n := 1; p.nameTaken(name); n++ {
+		name = fmt.Sprintf("%s_%s%d", base, suffix, n)
+	}
+	return name
+}
+
+func (p *ddlParser) nameTaken(name string) bool {
+	_, r := p.s.Relations[name]
+	_, i := p.s.Indexes[name]
+	_, s := p.s.Sequences[name]
+	for _, cs := range p.s.Constraints {
+		if _, ok := cs[name]; ok {
+			return true
+		}
+	}
+	return r || i || s
+}
+
+func (p *ddlParser) createTable(c *cursor) error {
+	ifNotExists := c.accept("if", "not", "exists")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	var (
+		r = Relation{
+			Type:    "table",
+			Columns: map[string]Column{},
+		}
+		columns []string
+		defs    = map[string]tableColumn{}
+		cons    []tableConstraint
+	)
+	if c.accept("partition", "of") {
+		parent, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		r.PartitionOf = p.qualify(parent)
+		if r.PartitionBound, err = partitionBound(c); err != nil {
+			return err
+		}
+	} else {
+		elems, err := c.group()
+		if err != nil {
+			return err
+		}
+		list, err := splitTokens(elems)
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			ec := &cursor{ts: e}
+			if ec.at("like") {
+				return ec.errorf("unsupported table element")
+			}
+			if isTableConstraint(ec) {
+				tc, err := p.tableConstraint(ec, "")
+				if err != nil {
+					return err
+				}
+				cons = append(cons, tc)
+				continue
+			}
+			name, col, err := p.column(ec)
+			if err != nil {
+				return err
+			}
+			columns = append(columns, name)
+			defs[name] = col
+		}
+		if c.accept("inherits") {
+			parents, err := c.group()
+			if err != nil {
+				return err
+			}
+			list, err := splitTokens(parents)
+			if err != nil {
+				return err
+			}
+			for _, pt := range list {
+				pc := &cursor{ts: pt}
+				parent, err := p.qname(pc)
+				if err != nil {
+					return err
+				}
+				if !pc.eof() {
+					return pc.errorf("unexpected input")
+				}
+				r.Inherits = append(r.Inherits, p.qualify(parent))
+			}
+		}
+	}
+	if c.accept("partition", "by") {
+		strategy, err := c.ident()
+		if err != nil {
+			return err
+		}
+		key, err := c.group()
+		if err != nil {
+			return err
+		}
+		r.Type = "partitioned table"
+		r.PartitionStrategy = strategy
+		list, err := splitTokens(key)
+		if err != nil {
+			return err
+		}
+		for _, k := range list {
+			r.PartitionKey = append(r.PartitionKey, joinTokens(k))
+		}
+	}
+	if c.accept("using") {
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+	}
+	if c.accept("with") {
+		if r.Options, err = options(c); err != nil {
+			return err
+		}
+	}
+	c.accept("without", "oids")
+	if c.accept("tablespace") {
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+	}
+
+	name, ok := p.local(q)
+	if !ok {
+		p.others[p.qualify(q)] = Relation{
+			Inherits:       r.Inherits,
+			PartitionOf:    r.PartitionOf,
+			PartitionBound: r.PartitionBound,
+		}
+		return nil
+	}
+	if _, ok := p.s.Relations[name]; ok {
+		if ifNotExists {
+			return nil
+		}
+		return c.errorf("relation %q already exists", name)
+	}
+	p.s.Relations[name] = r
+
+	// inherited columns come first
+	parents := r.Inherits
+	if r.PartitionOf.Name != "" {
+		parents = []QName{r.PartitionOf}
+	}
+	for _, pq := range parents {
+		_, parent, ok, err := p.relation(c, pq)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for _, cn := range parent.ColumnNames() {
+			if _, ok := r.Columns[cn]; !ok {
+				p.setColumn(name, cn, inheritedColumn(parent.Columns[cn]))
+			}
+		}
+	}
+	for _, cn := range columns {
+		col := defs[cn]
+		if old, ok := r.Columns[cn]; ok {
+			// merged with an inherited column
+			col.NotNull = col.NotNull || old.NotNull
+			if col.Default == "" && col.serial == "" {
+				col.Default = old.Default
+			}
+		}
+		if err := p.addColumn(c, name, cn, col); err != nil {
+			return err
+		}
+	}
+	for _, tc := range cons {
+		if err := p.addConstraint(c, name, tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partitionBound reads "FOR VALUES ..." or "DEFAULT".
+func partitionBound(c *cursor) (string, error) {
+	if c.accept("default") {
+		return "DEFAULT", nil
+	}
+	if !c.at("for", "values") {
+		return "", c.errorf(`expected "FOR VALUES" or "DEFAULT"`)
+	}
+	bound := c.until(func(t token) bool {
+		return t.is("partition") || t.is("using") || t.is("with") || t.is("tablespace")
+	})
+	var parts []string
+	for i, t := range bound {
+		s := t.raw
+		if t.typ == tokIdent {
+			switch t.text {
+			case "for", "values", "from", "to", "in", "with", "minvalue", "maxvalue":
+				s = strings.ToUpper(t.text)
+			case "modulus", "remainder":
+				s = t.text
+			}
+		}
+		if i > 0 && t.space {
+			s = " " + s
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ""), nil
+}
+
+// inheritedColumn is a column of a parent table as it is in the child.
+func inheritedColumn(col Column) Column {
+	col.AttNum = 0
+	col.Identity = ""
+	col.Privileges = nil
+	col.Comment = ""
+	return col
+}
+
+// setColumn adds or replaces a column, without anything else.
+func (p *ddlParser) setColumn(table, name string, col Column) {
+	r := p.s.Relations[table]
+	if old, ok := r.Columns[name]; ok {
+		col.AttNum = old.AttNum
+	} else {
+		p.attNums[table]++
+		col.AttNum = p.attNums[table]
+	}
+	r.Columns[name] = col
+}
+
+// addColumn adds a column, with its constraints, sequence, and inherited
+// copies.
+func (p *ddlParser) addColumn(c *cursor, table, name string, col tableColumn) error {
+	seq := QName{p.s.Name, p.uniqueName(table, []string{name}, "seq")}
+	if col.serial != "" {
+		p.s.Sequences[seq.Name] = newSequence(col.serial)
+		col.Default = "nextval(" + quoteLiteral(seq.String()) + "::regclass)"
+		col.NotNull = true
+	}
+	if col.Identity != "" {
+		s := newSequence(sequenceTypes[col.Type])
+		sc := &cursor{ts: col.identity}
+		if err := p.sequenceOptions(sc, &s, &seq); err != nil {
+			return err
+		}
+		p.s.Sequences[seq.Name] = s
+	}
+	if col.serial != "" || col.Identity != "" {
+		s := p.s.Sequences[seq.Name]
+		s.OwnedByTable = table
+		s.OwnedByColumn = name
+		p.s.Sequences[seq.Name] = s
+	}
+	p.setColumn(table, name, col.Column)
+	for _, tc := range col.constraints {
+		if err := p.addConstraint(c, table, tc); err != nil {
+			return err
+		}
+	}
+	for _, child := range p.children(table) {
+		if _, ok := p.s.Relations[child].Columns[name]; !ok {
+			p.setColumn(child, name, inheritedColumn(p.s.Relations[table].Columns[name]))
+		}
+	}
+	return nil
+}
+
+// dropColumn removes a column, with everything which uses it.
+func (p *ddlParser) dropColumn(table, name string) {
+	delete(p.s.Relations[table].Columns, name)
+	for n, i := range p.s.Indexes {
+		if i.Table == table && (hasString(i.Columns, name) || hasString(i.Include, name)) {
+			delete(p.s.Indexes, n)
+		}
+	}
+	for n, c := range p.s.Constraints[table] {
+		if hasString(c.Columns, name) {
+			p.dropConstraint(table, n)
+		}
+	}
+	for n, s := range p.s.Sequences {
+		if s.OwnedByTable == table && s.OwnedByColumn == name {
+			delete(p.s.Sequences, n)
+		}
+	}
+	for _, child := range p.children(table) {
+		p.dropColumn(child, name)
+	}
+}
+
+// alterColumn changes a column, and unless only is set, the column in the
+// children.
+func (p *ddlParser) alterColumn(table, name string, only bool, f func(*Column)) {
+	r := p.s.Relations[table]
+	col, ok := r.Columns[name]
+	if !ok {
+		return
+	}
+	f(&col)
+	r.Columns[name] = col
+	if only {
+		return
+	}
+	for _, child := range p.children(table) {
+		p.alterColumn(child, name, false, f)
+	}
+}
+
+// dropRelation removes a relation, with its partitions, children, and
+// everything else which depends on it.
+func (p *ddlParser) dropRelation(name string) {
+	delete(p.s.Relations, name)
+	delete(p.attNums, name)
+	for _, child := range p.children(name) {
+		p.dropRelation(child)
+	}
+	q := QName{p.s.Name, name}
+	for n, i := range p.s.Indexes {
+		if i.Table == name {
+			delete(p.s.Indexes, n)
+		}
+	}
+	delete(p.s.Constraints, name)
+	for _, cs := range p.s.Constraints {
+		for n, c := range cs {
+			if c.RefTable == q {
+				delete(cs, n)
+			}
+		}
+	}
+	for n, s := range p.s.Sequences {
+		if s.OwnedByTable == name {
+			delete(p.s.Sequences, n)
+		}
+	}
+}
+
+// dropConstraint removes a constraint, and the index it owns.
+func (p *ddlParser) dropConstraint(table, name string) {
+	c := p.s.Constraints[table][name]
+	delete(p.s.Constraints[table], name)
+	if i, ok := p.s.Indexes[c.Index]; ok && i.Table == table && i.Constraint == name {
+		delete(p.s.Indexes, c.Index)
+	}
+}
+
+// tableColumn is a column with what's needed to add it.
+type tableColumn struct {
+	Column
+	// serial is the sequence type of a serial column
+	serial string
+	// identity has the sequence options of an identity column
+	identity    []token
+	constraints []tableConstraint
+}
+
+var serialTypes = map[string]string{
+	"smallserial": "int2",
+	"serial2":     "int2",
+	"serial":      "int4",
+	"serial4":     "int4",
+	"bigserial":   "int8",
+	"serial8":     "int8",
+}
+
+// sequenceTypes are the sequence types for the column types
+var sequenceTypes = map[string]string{
+	"int2": "smallint",
+	"int4": "integer",
+	"int8": "bigint",
+}
+
+// columnEnd are the keywords which end an expression in a column
+// definition
+var columnEnd = set(
+	"check", "collate", "constraint", "default", "deferrable", "generated",
+	"initially", "not", "null", "primary", "references", "unique",
+)
+
+func isColumnEnd(t token) bool {
+	return t.typ == tokIdent && columnEnd[t.text]
+}
+
+// column reads a column definition, from CREATE TABLE or ADD COLUMN.
+func (p *ddlParser) column(c *cursor) (string, tableColumn, error) {
+	var col tableColumn
+	name, err := c.ident()
+	if err != nil {
+		return "", col, err
+	}
+	t, err := c.typeName()
+	if err != nil {
+		return "", col, err
+	}
+	if st, ok := serialTypes[t.name.Name]; ok && t.name.Schema == "" && t.array == 0 {
+		col.serial = sequenceTypes[st]
+		t = typeSpec{name: QName{Name: st}}
+	}
+	col.Type, col.TypeName, col.FullType, col.Array = p.resolveType(t)
+	for !c.eof() {
+		var cname string
+		if c.accept("constraint") {
+			if cname, err = c.ident(); err != nil {
+				return "", col, err
+			}
+		}
+		switch {
+		case c.accept("not", "null"):
+			col.NotNull = true
+		case c.accept("null"):
+		case c.accept("default"):
+			col.Default = joinTokens(c.until(isColumnEnd))
+			if col.Default == "" {
+				if err := c.expect("null"); err != nil {
+					return "", col, err
+				}
+			}
+		case c.accept("generated", "always", "as", "identity"):
+			col.Identity = "always"
+		case c.accept("generated", "by", "default", "as", "identity"):
+			col.Identity = "by default"
+		case c.accept("generated", "always", "as"):
+			expr, err := c.group()
+			if err != nil {
+				return "", col, err
+			}
+			col.Generated = joinTokens(expr)
+			if err := c.expect("stored"); err != nil {
+				return "", col, err
+			}
+		case c.accept("collate"):
+			if _, err := p.qname(c); err != nil {
+				return "", col, err
+			}
+		default:
+			tc, err := p.tableConstraint(c, name)
+			if err != nil {
+				return "", col, err
+			}
+			tc.name = cname
+			col.constraints = append(col.constraints, tc)
+		}
+		if col.Identity != "" {
+			col.NotNull = true
+			if c.at("(") {
+				if col.identity, err = c.group(); err != nil {
+					return "", col, err
+				}
+			}
+		}
+	}
+	return name, col, nil
+}
+
+// tableConstraint is a constraint as it is in the SQL.
+type tableConstraint struct {
+	name string
+	Constraint
+	// check is the expression of a check constraint
+	check []token
+}
+
+func isTableConstraint(c *cursor) bool {
+	for _, w := range []string{"constraint", "primary", "unique", "check", "foreign", "exclude"} {
+		if c.at(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableConstraint reads a table constraint, or if column is set, a column
+// constraint.
+func (p *ddlParser) tableConstraint(c *cursor, column string) (tableConstraint, error) {
+	tc := tableConstraint{
+		Constraint: Constraint{Validated: true},
+	}
+	var err error
+	if column == "" && c.accept("constraint") {
+		if tc.name, err = c.ident(); err != nil {
+			return tc, err
+		}
+	}
+	columns := func() ([]string, error) {
+		if column != "" {
+			return []string{column}, nil
+		}
+		return c.identList()
+	}
+	switch {
+	case c.accept("primary", "key"):
+		tc.Type = "primary key"
+		tc.Columns, err = columns()
+	case c.accept("unique"):
+		tc.Type = "unique"
+		tc.Columns, err = columns()
+	case c.accept("check"):
+		tc.Type = "check"
+		tc.check, err = c.group()
+	case column == "" && c.accept("foreign", "key"):
+		tc.Type = "foreign key"
+		if tc.Columns, err = columns(); err != nil {
+			return tc, err
+		}
+		err = c.expect("references")
+	case column != "" && c.accept("references"):
+		tc.Type = "foreign key"
+		tc.Columns = []string{column}
+	case c.accept("exclude"):
+		tc.Type = "exclusion"
+		tc.Definition = "EXCLUDE " + joinTokens(c.until(func(t token) bool {
+			return t.is("deferrable") || t.is("not") || t.is("initially")
+		}))
+	default:
+		return tc, c.errorf("expected a constraint")
+	}
+	if err != nil {
+		return tc, err
+	}
+	if tc.Type == "foreign key" {
+		ref, err := p.qname(c)
+		if err != nil {
+			return tc, err
+		}
+		tc.RefTable = p.qualify(ref)
+		if c.at("(") {
+			if tc.RefColumns, err = c.identList(); err != nil {
+				return tc, err
+			}
+		}
+		if c.accept("match") {
+			if _, err := c.ident(); err != nil {
+				return tc, err
+			}
+		}
+		tc.OnUpdate, tc.OnDelete = "no action", "no action"
+		for {
+			var action *string
+			switch {
+			case c.accept("on", "update"):
+				action = &tc.OnUpdate
+			case c.accept("on", "delete"):
+				action = &tc.OnDelete
+			}
+			if action == nil {
+				break
+			}
+			if *action, err = referentialAction(c); err != nil {
+				return tc, err
+			}
+		}
+	}
+	if tc.Type == "check" && c.accept("no", "inherit") {
+		tc.Definition = " NO INHERIT"
+	}
+	for {
+		switch {
+		case c.accept("deferrable"):
+			tc.Deferrable = true
+		case c.accept("not", "deferrable"):
+			tc.Deferrable = false
+		case c.accept("initially", "deferred"):
+			tc.InitiallyDeferred = true
+		case c.accept("initially", "immediate"):
+			tc.InitiallyDeferred = false
+		case c.accept("not", "valid"):
+			tc.Validated = false
+		default:
+			return tc, nil
+		}
+	}
+}
+
+func referentialAction(c *cursor) (string, error) {
+	for _, a := range []string{"no action", "restrict", "cascade", "set null", "set default"} {
+		if c.accept(strings.Fields(a)...) {
+			if c.at("(") {
+				// SET NULL (columns)
+				if _, err := c.group(); err != nil {
+					return "", err
+				}
+			}
+			return a, nil
+		}
+	}
+	return "", c.errorf("expected a referential action")
+}
+
+// addConstraint adds a constraint, and the index of a primary key or unique
+// constraint.
+func (p *ddlParser) addConstraint(c *cursor, table string, tc tableConstraint) error {
+	r := p.s.Relations[table]
+	con := tc.Constraint
+	con.Table = table
+	if con.Type == "check" {
+		con.Columns = exprColumns(tc.check, r.Columns)
+		con.Definition = "CHECK (" + parens(joinTokens(tc.check)) + ")" + con.Definition
+		if !con.Validated {
+			con.Definition += " NOT VALID"
+		}
+	}
+	for _, col := range con.Columns {
+		if _, ok := r.Columns[col]; !ok {
+			return c.errorf("column %q does not exist", col)
+		}
+	}
+	name := tc.name
+	if name == "" {
+		switch con.Type {
+		case "primary key":
+			name = p.uniqueName(table, nil, "pkey")
+		case "unique":
+			name = p.uniqueName(table, con.Columns, "key")
+		case "foreign key":
+			name = p.uniqueName(table, con.Columns, "fkey")
+		case "check":
+			var cols []string
+			if len(con.Columns) == 1 {
+				cols = con.Columns
+			}
+			name = p.uniqueName(table, cols, "check")
+		case "exclusion":
+			name = p.uniqueName(table, nil, "excl")
+		}
+	}
+	if _, ok := p.s.Constraints[table][name]; ok {
+		return c.errorf("constraint %q already exists", name)
+	}
+	if con.Type == "primary key" {
+		for _, other := range p.s.Constraints[table] {
+			if other.Type == "primary key" {
+				return c.errorf("multiple primary keys for table %q are not allowed", table)
+			}
+		}
+	}
+	switch con.Type {
+	case "primary key", "unique":
+		con.Index = name
+		con.Definition = constraintDef(con)
+		idx := Index{
+			Table:     table,
+			Type:      "btree",
+			Unique:    true,
+			Primary:   con.Type == "primary key",
+			Columns:   con.Columns,
+			Immediate: !con.Deferrable,
+			Valid:     true,
+			Ready:     true,
+			Live:      true,
+		}
+		for _, col := range con.Columns {
+			idx.Keys = append(idx.Keys, IndexKey{Column: col})
+			if idx.Primary {
+				p.alterColumn(table, col, true, func(c *Column) { c.NotNull = true })
+			}
+		}
+		idx.Constraint = name
+		p.addIndex(name, idx)
+	case "exclusion":
+		if !con.Validated {
+			con.Definition += " NOT VALID"
+		}
+	}
+	if p.s.Constraints[table] == nil {
+		p.s.Constraints[table] = map[string]Constraint{}
+	}
+	p.s.Constraints[table][name] = con
+	return nil
+}
+
+// exprColumns finds the columns used in an expression, in order.
+func exprColumns(expr []token, columns map[string]Column) []string {
+	var cols []string
+	for i, t := range expr {
+		if t.typ != tokIdent && t.typ != tokQuoted {
+			continue
+		}
+		if i > 0 && expr[i-1].is(".") || i+1 < len(expr) && (expr[i+1].is("(") || expr[i+1].is(".")) {
+			continue
+		}
+		if _, ok := columns[t.text]; ok && !hasString(cols, t.text) {
+			cols = append(cols, t.text)
+		}
+	}
+	return cols
+}
+
+func (p *ddlParser) alterTable(c *cursor) error {
+	ifExists := c.accept("if", "exists")
+	only := c.accept("only")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	c.accept("*")
+	name, ok := p.local(q)
+	if !ok {
+		c.rest()
+		return nil
+	}
+	if _, ok := p.s.Relations[name]; !ok {
+		if ifExists {
+			c.rest()
+			return nil
+		}
+		return c.errorf("relation %q does not exist", name)
+	}
+	if c.at("attach", "partition") || c.at("detach", "partition") {
+		return p.partitionAction(c, name)
+	}
+	list, err := splitTokens(c.rest())
+	if err != nil {
+		return err
+	}
+	for _, action := range list {
+		ac := &cursor{ts: action}
+		if err := p.alterTableAction(ac, name, only); err != nil {
+			return err
+		}
+		if !ac.eof() {
+			return ac.errorf("unexpected input")
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) alterTableAction(c *cursor, table string, only bool) error {
+	r := p.s.Relations[table]
+	switch {
+	case c.accept("add"):
+		if isTableConstraint(c) {
+			tc, err := p.tableConstraint(c, "")
+			if err != nil {
+				return err
+			}
+			return p.addConstraint(c, table, tc)
+		}
+		c.accept("column")
+		ifNotExists := c.accept("if", "not", "exists")
+		name, col, err := p.column(c)
+		if err != nil {
+			return err
+		}
+		if _, ok := r.Columns[name]; ok {
+			if ifNotExists {
+				return nil
+			}
+			return c.errorf("column %q already exists", name)
+		}
+		return p.addColumn(c, table, name, col)
+
+	case c.accept("drop", "constraint"):
+		ifExists := c.accept("if", "exists")
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if !c.accept("cascade") {
+			c.accept("restrict")
+		}
+		if _, ok := p.s.Constraints[table][name]; !ok {
+			if ifExists {
+				return nil
+			}
+			return c.errorf("constraint %q does not exist", name)
+		}
+		p.dropConstraint(table, name)
+		return nil
+
+	case c.accept("drop"):
+		c.accept("column")
+		ifExists := c.accept("if", "exists")
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if !c.accept("cascade") {
+			c.accept("restrict")
+		}
+		if _, ok := r.Columns[name]; !ok {
+			if ifExists {
+				return nil
+			}
+			return c.errorf("column %q does not exist", name)
+		}
+		p.dropColumn(table, name)
+		return nil
+
+	case c.accept("alter"):
+		c.accept("column")
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if _, ok := r.Columns[name]; !ok {
+			return c.errorf("column %q does not exist", name)
+		}
+		return p.alterColumnAction(c, table, name, only)
+
+	case c.accept("validate", "constraint"):
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		con, ok := p.s.Constraints[table][name]
+		if !ok {
+			return c.errorf("constraint %q does not exist", name)
+		}
+		con.Validated = true
+		con.Definition = strings.TrimSuffix(con.Definition, " NOT VALID")
+		p.s.Constraints[table][name] = con
+		return nil
+
+	case c.accept("enable", "row", "level", "security"):
+		r.RowSecurity = true
+	case c.accept("disable", "row", "level", "security"):
+		r.RowSecurity = false
+	case c.accept("force", "row", "level", "security"):
+		r.ForceRowSecurity = true
+	case c.accept("no", "force", "row", "level", "security"):
+		r.ForceRowSecurity = false
+
+	case c.at("enable"), c.at("disable"):
+		enabled := "disabled"
+		switch {
+		case c.accept("enable", "replica"):
+			enabled = "replica"
+		case c.accept("enable", "always"):
+			enabled = "always"
+		case c.accept("enable"):
+			enabled = "origin"
+		default:
+			c.accept("disable")
+		}
+		if err := c.expect("trigger"); err != nil {
+			return err
+		}
+		var name string
+		if !c.accept("all") && !c.accept("user") {
+			var err error
+			if name, err = c.ident(); err != nil {
+				return err
+			}
+			if _, ok := r.Triggers[name]; !ok {
+				return c.errorf("trigger %q does not exist", name)
+			}
+		}
+		for n, t := range r.Triggers {
+			if name == "" || n == name {
+				t.Enabled = enabled
+				r.Triggers[n] = t
+			}
+		}
+
+	case c.accept("cluster", "on"):
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		return p.setIndexFlag(c, table, name, func(i *Index, on bool) { i.Clustered = on })
+	case c.accept("set", "without", "cluster"):
+		return p.setIndexFlag(c, table, "", func(i *Index, on bool) { i.Clustered = on })
+
+	case c.accept("replica", "identity"):
+		name := ""
+		if c.accept("using", "index") {
+			var err error
+			if name, err = c.ident(); err != nil {
+				return err
+			}
+		} else if !c.accept("default") && !c.accept("full") && !c.accept("nothing") {
+			return c.errorf("expected a replica identity")
+		}
+		return p.setIndexFlag(c, table, name, func(i *Index, on bool) { i.ReplicaIdentity = on })
+
+	case c.accept("set", "("):
+		c.i--
+		opts, err := options(c)
+		if err != nil {
+			return err
+		}
+		for _, o := range opts {
+			k := o[:strings.Index(o, "=")]
+			r.Options = setOption(r.Options, k, o)
+		}
+		viewOptions(&r)
+	case c.accept("reset"):
+		keys, err := c.group()
+		if err != nil {
+			return err
+		}
+		list, err := splitTokens(keys)
+		if err != nil {
+			return err
+		}
+		for _, k := range list {
+			r.Options = setOption(r.Options, joinTokens(k), "")
+		}
+		viewOptions(&r)
+
+	case c.accept("inherit"):
+		parent, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		r.Inherits = append(r.Inherits, p.qualify(parent))
+	case c.accept("no", "inherit"):
+		parent, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		var inherits []QName
+		for _, i := range r.Inherits {
+			if i != p.qualify(parent) {
+				inherits = append(inherits, i)
+			}
+		}
+		r.Inherits = inherits
+
+	case c.accept("set", "logged"), c.accept("set", "unlogged"), c.accept("set", "tablespace"), c.accept("set", "access", "method"):
+		c.rest()
+	default:
+		return c.errorf("unsupported ALTER TABLE action")
+	}
+	p.s.Relations[table] = r
+	return nil
+}
+
+func (p *ddlParser) alterColumnAction(c *cursor, table, name string, only bool) error {
+	var f func(*Column)
+	switch {
+	case c.accept("set", "data", "type"), c.accept("type"):
+		t, err := c.typeName()
+		if err != nil {
+			return err
+		}
+		short, typeName, full, array := p.resolveType(t)
+		if c.accept("collate") {
+			if _, err := p.qname(c); err != nil {
+				return err
+			}
+		}
+		if c.accept("using") {
+			c.rest()
+		}
+		f = func(col *Column) {
+			col.Type, col.TypeName, col.FullType, col.Array = short, typeName, full, array
+		}
+	case c.accept("set", "default"):
+		def := joinTokens(c.rest())
+		f = func(col *Column) { col.Default = def }
+	case c.accept("drop", "default"):
+		f = func(col *Column) { col.Default = "" }
+	case c.accept("set", "not", "null"):
+		f = func(col *Column) { col.NotNull = true }
+	case c.accept("drop", "not", "null"):
+		f = func(col *Column) { col.NotNull = false }
+	case c.accept("drop", "expression"):
+		c.accept("if", "exists")
+		f = func(col *Column) { col.Generated = "" }
+	case c.accept("add", "generated"):
+		identity := "by default"
+		if c.accept("always") {
+			identity = "always"
+		} else if err := c.expect("by", "default"); err != nil {
+			return err
+		}
+		if err := c.expect("as", "identity"); err != nil {
+			return err
+		}
+		col := p.s.Relations[table].Columns[name]
+		seq := QName{p.s.Name, p.uniqueName(table, []string{name}, "seq")}
+		s := newSequence(sequenceTypes[col.Type])
+		if c.at("(") {
+			opts, err := c.group()
+			if err != nil {
+				return err
+			}
+			if err := p.sequenceOptions(&cursor{ts: opts}, &s, &seq); err != nil {
+				return err
+			}
+		}
+		s.OwnedByTable, s.OwnedByColumn = table, name
+		p.s.Sequences[seq.Name] = s
+		f = func(col *Column) {
+			col.Identity = identity
+			col.NotNull = true
+		}
+		only = true
+	case c.accept("set", "generated", "always"):
+		f = func(col *Column) { col.Identity = "always" }
+		only = true
+	case c.accept("set", "generated", "by", "default"):
+		f = func(col *Column) { col.Identity = "by default" }
+		only = true
+	case c.accept("drop", "identity"):
+		c.accept("if", "exists")
+		for n, s := range p.s.Sequences {
+			if s.OwnedByTable == table && s.OwnedByColumn == name {
+				delete(p.s.Sequences, n)
+			}
+		}
+		f = func(col *Column) { col.Identity = "" }
+		only = true
+	case c.accept("set", "statistics"), c.accept("set", "storage"), c.accept("set", "compression"), c.accept("set"), c.accept("reset"):
+		c.rest()
+		return nil
+	default:
+		return c.errorf("unsupported ALTER COLUMN action")
+	}
+	p.alterColumn(table, name, only, f)
+	return nil
+}
+
+// partitionAction handles ATTACH PARTITION and DETACH PARTITION
+func (p *ddlParser) partitionAction(c *cursor, table string) error {
+	attach := c.accept("attach", "partition")
+	if !attach {
+		c.accept("detach", "partition")
+	}
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	parent := QName{p.s.Name, table}
+	var bound string
+	if attach {
+		if bound, err = partitionBound(c); err != nil {
+			return err
+		}
+	} else if !c.accept("concurrently") {
+		c.accept("finalize")
+	}
+	name, ok := p.local(q)
+	if !ok {
+		if attach {
+			p.others[p.qualify(q)] = Relation{PartitionOf: parent, PartitionBound: bound}
+		} else {
+			delete(p.others, p.qualify(q))
+		}
+		return nil
+	}
+	r, ok := p.s.Relations[name]
+	if !ok {
+		return c.errorf("relation %q does not exist", name)
+	}
+	if attach {
+		r.PartitionOf, r.PartitionBound = parent, bound
+	} else {
+		r.PartitionOf, r.PartitionBound = QName{}, ""
+		for n, i := range p.s.Indexes {
+			if i.Table == name {
+				i.PartitionOf = QName{}
+				p.s.Indexes[n] = i
+			}
+		}
+	}
+	p.s.Relations[name] = r
+	return nil
+}
+
+// setIndexFlag sets a flag on the index, and clears it on all other indexes
+// of the table.
+func (p *ddlParser) setIndexFlag(c *cursor, table, index string, f func(*Index, bool)) error {
+	if index != "" {
+		if i, ok := p.s.Indexes[index]; !ok || i.Table != table {
+			return c.errorf("index %q does not exist", index)
+		}
+	}
+	for n, i := range p.s.Indexes {
+		if i.Table == table {
+			f(&i, n == index)
+			p.s.Indexes[n] = i
+		}
+	}
+	return nil
+}
+
+// setOption replaces the "key=..." option, or adds it. An empty option
+// removes it.
+func setOption(opts []string, key, option string) []string {
+	var res []string
+	for _, o := range opts {
+		if strings.HasPrefix(o, key+"=") {
+			if option != "" {
+				res = append(res, option)
+				option = ""
+			}
+			continue
+		}
+		res = append(res, o)
+	}
+	if option != "" {
+		res = append(res, option)
+	}
+	return res
+}
+
+// viewOptions sets the fields which come from the options of a view.
+func viewOptions(r *Relation) {
+	r.CheckOption = relOption(r.Options, "check_option")
+	r.SecurityBarrier = isTrue(relOption(r.Options, "security_barrier"))
+	r.SecurityInvoker = isTrue(relOption(r.Options, "security_invoker"))
+}
+
+// cluster handles CLUSTER table USING index
+func (p *ddlParser) cluster(c *cursor) error {
+	c.accept("verbose")
+	if c.eof() {
+		return nil
+	}
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	if !c.accept("using") {
+		return nil
+	}
+	index, err := c.ident()
+	if err != nil {
+		return err
+	}
+	name, _, ok, err := p.relation(c, q)
+	if !ok {
+		return err
+	}
+	return p.setIndexFlag(c, name, index, func(i *Index, on bool) { i.Clustered = on })
+}
+
+func (p *ddlParser) createIndex(c *cursor, unique bool) error {
+	c.accept("concurrently")
+	ifNotExists := c.accept("if", "not", "exists")
+	var (
+		name string
+		err  error
+	)
+	if !c.at("on") {
+		if name, err = c.ident(); err != nil {
+			return err
+		}
+	}
+	if err := c.expect("on"); err != nil {
+		return err
+	}
+	c.accept("only")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	idx := Index{
+		Type:      "btree",
+		Unique:    unique,
+		Immediate: true,
+		Valid:     true,
+		Ready:     true,
+		Live:      true,
+	}
+	if c.accept("using") {
+		if idx.Type, err = c.ident(); err != nil {
+			return err
+		}
+	}
+	keys, err := c.group()
+	if err != nil {
+		return err
+	}
+	list, err := splitTokens(keys)
+	if err != nil {
+		return err
+	}
+	for _, k := range list {
+		key, err := indexKeyOf(&cursor{ts: k})
+		if err != nil {
+			return err
+		}
+		idx.Keys = append(idx.Keys, key)
+		if key.Column != "" {
+			idx.Columns = append(idx.Columns, key.Column)
+		} else {
+			idx.Columns = append(idx.Columns, key.Expression)
+		}
+	}
+	if c.accept("include") {
+		if idx.Include, err = c.identList(); err != nil {
+			return err
+		}
+	}
+	if c.accept("nulls", "not", "distinct") {
+		idx.NullsNotDistinct = true
+	} else {
+		c.accept("nulls", "distinct")
+	}
+	if c.accept("with") {
+		if _, err := options(c); err != nil {
+			return err
+		}
+	}
+	if c.accept("tablespace") {
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+	}
+	if c.accept("where") {
+		idx.Predicate = parens(joinTokens(c.rest()))
+	}
+
+	table, _, ok, err := p.relation(c, q)
+	if !ok {
+		return err
+	}
+	idx.Table = table
+	if name == "" {
+		var cols []string
+		for _, k := range idx.Keys {
+			cols = append(cols, indexColumnName(k))
+		}
+		name = p.uniqueName(table, cols, "idx")
+	}
+	if p.nameTaken(name) {
+		if ifNotExists {
+			return nil
+		}
+		return c.errorf("relation %q already exists", name)
+	}
+	p.addIndex(name, idx)
+	return nil
+}
+
+// indexKeyOf reads a key of CREATE INDEX.
+func indexKeyOf(c *cursor) (IndexKey, error) {
+	var k IndexKey
+	switch {
+	case c.at("("):
+		expr, err := c.group()
+		if err != nil {
+			return k, err
+		}
+		k.Expression = joinTokens(expr)
+	case c.peekAt(1).is("(") || (c.peekAt(1).is(".") && c.peekAt(3).is("(")):
+		// a function call
+		start := c.i
+		if _, err := c.qname(); err != nil {
+			return k, err
+		}
+		if _, err := c.group(); err != nil {
+			return k, err
+		}
+		k.Expression = joinTokens(c.ts[start:c.i])
+	default:
+		var err error
+		if k.Column, err = c.ident(); err != nil {
+			return k, err
+		}
+	}
+	if c.accept("collate") {
+		q, err := c.qname()
+		if err != nil {
+			return k, err
+		}
+		k.Collation = q.Name
+	}
+	if t := c.peek(); !c.eof() && (t.typ == tokIdent || t.typ == tokQuoted) && !t.is("asc") && !t.is("desc") && !t.is("nulls") {
+		q, err := c.qname()
+		if err != nil {
+			return k, err
+		}
+		k.OpClass = q.String()
+	}
+	if c.accept("desc") {
+		k.Descending = true
+		k.NullsFirst = true
+	} else {
+		c.accept("asc")
+	}
+	if c.accept("nulls", "first") {
+		k.NullsFirst = true
+	} else if c.accept("nulls", "last") {
+		k.NullsFirst = false
+	}
+	if !c.eof() {
+		return k, c.errorf("unexpected input")
+	}
+	return k, nil
+}
+
+// indexColumnName is what PostgreSQL uses in the name of an index for a
+// key: the column, or the function name of an expression.
+func indexColumnName(k IndexKey) string {
+	if k.Column != "" {
+		return k.Column
+	}
+	ts, err := lex(k.Expression)
+	if err == nil && len(ts) == 1 && len(ts[0]) > 1 && ts[0][1].is("(") && ts[0][0].typ == tokIdent {
+		return ts[0][0].text
+	}
+	return "expr"
+}
+
+func (p *ddlParser) addIndex(name string, idx Index) {
+	idx.Partitioned = p.s.Relations[idx.Table].Type == "partitioned table"
+	idx.Definition = ""
+	idx.Definition = renderer{p.s.Name}.createIndex(name, idx)
+	p.s.Indexes[name] = idx
+}
+
+func (p *ddlParser) alterIndex(c *cursor) error {
+	c.accept("if", "exists")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	if !c.accept("attach", "partition") {
+		return c.errorf("unsupported ALTER INDEX action")
+	}
+	part, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	parent, ok := p.local(q)
+	if !ok {
+		return nil
+	}
+	name, ok := p.local(part)
+	i, found := p.s.Indexes[name]
+	if !ok || !found {
+		return c.errorf("index %q does not exist", name)
+	}
+	i.PartitionOf = QName{p.s.Name, parent}
+	p.s.Indexes[name] = i
+	return nil
+}
+
+func (p *ddlParser) createView(c *cursor, typ string) error {
+	c.accept("if", "not", "exists")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	r := Relation{
+		Type:      typ,
+		Columns:   map[string]Column{},
+		Populated: typ == "materialized view",
+	}
+	if c.at("(") {
+		if _, err := c.identList(); err != nil {
+			return err
+		}
+	}
+	if c.accept("using") {
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+	}
+	if c.accept("with") {
+		if r.Options, err = options(c); err != nil {
+			return err
+		}
+	}
+	if c.accept("tablespace") {
+		if _, err := c.ident(); err != nil {
+			return err
+		}
+	}
+	if err := c.expect("as"); err != nil {
+		return err
+	}
+	if !c.at("select") && !c.at("values") && !c.at("with") && !c.at("table") && !c.at("(") {
+		return c.errorf("expected a query")
+	}
+	query := c.rest()
+	// trailing WITH ... CHECK OPTION and WITH [NO] DATA
+	tail := func(words ...string) bool {
+		if len(query) < len(words) {
+			return false
+		}
+		for i, w := range words {
+			if !query[len(query)-len(words)+i].is(w) {
+				return false
+			}
+		}
+		query = query[:len(query)-len(words)]
+		return true
+	}
+	switch {
+	case tail("with", "no", "data"):
+		r.Populated = false
+	case tail("with", "data"):
+	case tail("with", "cascaded", "check", "option"), tail("with", "check", "option"):
+		r.Options = setOption(r.Options, "check_option", "check_option=cascaded")
+	case tail("with", "local", "check", "option"):
+		r.Options = setOption(r.Options, "check_option", "check_option=local")
+	}
+	r.Definition = joinTokens(query)
+	viewOptions(&r)
+
+	name, ok := p.local(q)
+	if !ok {
+		return nil
+	}
+	p.s.Relations[name] = r
+	return nil
+}
+
+// newSequence is a sequence with the defaults for its type. An empty type
+// is bigint.
+func newSequence(typ string) Sequence {
+	if typ == "" {
+		typ = "bigint"
+	}
+	s := Sequence{
+		Type:        typ,
+		IncrementBy: 1,
+		Cache:       1,
+	}
+	sequenceDefaults(&s, nil)
+	return s
+}
+
+// sequenceDefaults sets the minimum, maximum, and start of a sequence,
+// unless they are in set.
+func sequenceDefaults(s *Sequence, set map[string]bool) {
+	var min, max int
+	switch s.Type {
+	case "smallint":
+		min, max = -1<<15, 1<<15-1
+	case "integer":
+		min, max = -1<<31, 1<<31-1
+	default:
+		min, max = -1<<63, 1<<63-1
+	}
+	if !set["minvalue"] {
+		s.MinValue = 1
+		if s.IncrementBy < 0 {
+			s.MinValue = min
+		}
+	}
+	if !set["maxvalue"] {
+		s.MaxValue = max
+		if s.IncrementBy < 0 {
+			s.MaxValue = -1
+		}
+	}
+	if !set["start"] {
+		s.Start = s.MinValue
+		if s.IncrementBy < 0 {
+			s.Start = s.MaxValue
+		}
+	}
+}
+
+func (p *ddlParser) createSequence(c *cursor) error {
+	c.accept("if", "not", "exists")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	name, ok := p.local(q)
+	s := Sequence{Type: "bigint", IncrementBy: 1, Cache: 1}
+	set := map[string]bool{}
+	if err := p.sequenceOptionsSet(c, &s, nil, set); err != nil {
+		return err
+	}
+	sequenceDefaults(&s, set)
+	if ok {
+		p.s.Sequences[name] = s
+	}
+	return nil
+}
+
+func (p *ddlParser) alterSequence(c *cursor) error {
+	ifExists := c.accept("if", "exists")
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	name, ok := p.local(q)
+	if !ok {
+		c.rest()
+		return nil
+	}
+	s, ok := p.s.Sequences[name]
+	if !ok {
+		if ifExists {
+			c.rest()
+			return nil
+		}
+		return c.errorf("sequence %q does not exist", name)
+	}
+	var (
+		old = s
+		set = map[string]bool{"minvalue": true, "maxvalue": true, "start": true}
+	)
+	if err := p.sequenceOptionsSet(c, &s, nil, set); err != nil {
+		return err
+	}
+	if s.Type != old.Type {
+		// limits which were the defaults of the old type follow the type
+		def := Sequence{Type: old.Type, IncrementBy: s.IncrementBy}
+		sequenceDefaults(&def, nil)
+		set["minvalue"] = set["minvalue"] && s.MinValue != def.MinValue
+		set["maxvalue"] = set["maxvalue"] && s.MaxValue != def.MaxValue
+	}
+	sequenceDefaults(&s, set)
+	p.s.Sequences[name] = s
+	return nil
+}
+
+// sequenceOptions reads the options of an identity column into s. The name
+// can be changed with SEQUENCE NAME.
+func (p *ddlParser) sequenceOptions(c *cursor, s *Sequence, name *QName) error {
+	set := map[string]bool{}
+	if err := p.sequenceOptionsSet(c, s, name, set); err != nil {
+		return err
+	}
+	if !c.eof() {
+		return c.errorf("unexpected input")
+	}
+	sequenceDefaults(s, set)
+	return nil
+}
+
+// sequenceOptionsSet reads the options of CREATE and ALTER SEQUENCE. The
+// options which are given are true in set, NO MINVALUE and NO MAXVALUE make
+// them false.
+func (p *ddlParser) sequenceOptionsSet(c *cursor, s *Sequence, name *QName, set map[string]bool) error {
+	for !c.eof() {
+		var err error
+		switch {
+		case c.accept("as"):
+			var t typeSpec
+			if t, err = c.typeName(); err == nil {
+				short, _, _, _ := p.resolveType(t)
+				if s.Type = sequenceTypes[short]; s.Type == "" {
+					return c.errorf("invalid sequence type")
+				}
+			}
+		case c.accept("increment"):
+			c.accept("by")
+			s.IncrementBy, err = c.number()
+		case c.accept("minvalue"):
+			s.MinValue, err = c.number()
+			set["minvalue"] = true
+		case c.accept("no", "minvalue"):
+			set["minvalue"] = false
+		case c.accept("maxvalue"):
+			s.MaxValue, err = c.number()
+			set["maxvalue"] = true
+		case c.accept("no", "maxvalue"):
+			set["maxvalue"] = false
+		case c.accept("start"):
+			c.accept("with")
+			s.Start, err = c.number()
+			set["start"] = true
+		case c.accept("restart"):
+			c.accept("with")
+			if c.peek().typ == tokNumber || c.at("-") {
+				_, err = c.number()
+			}
+		case c.accept("cache"):
+			s.Cache, err = c.number()
+		case c.accept("cycle"):
+			s.Cycle = true
+		case c.accept("no", "cycle"):
+			s.Cycle = false
+		case c.accept("owned", "by"):
+			if c.accept("none") {
+				s.OwnedByTable, s.OwnedByColumn = "", ""
+				break
+			}
+			var parts []string
+			if parts, err = c.dotted(); err != nil {
+				break
+			}
+			if len(parts) < 2 {
+				return c.errorf("expected table.column")
+			}
+			col := parts[len(parts)-1]
+			q := QName{Name: parts[len(parts)-2]}
+			if len(parts) > 2 {
+				q.Schema = parts[len(parts)-3]
+			}
+			if table, ok := p.local(q); ok {
+				s.OwnedByTable, s.OwnedByColumn = table, col
+			}
+		case name != nil && c.accept("sequence", "name"):
+			var q QName
+			if q, err = p.qname(c); err == nil {
+				*name = p.qualify(q)
+			}
+		case c.accept("logged"), c.accept("unlogged"):
+		default:
+			return c.errorf("unsupported sequence option")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) createFunction(c *cursor, kind string, replace bool) error {
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	argList, err := c.group()
+	if err != nil {
+		return err
+	}
+	f := Function{
+		Name:       q.Name,
+		Kind:       kind,
+		Volatility: "volatile",
+		Parallel:   "unsafe",
+		Cost:       100,
+	}
+	var outs []string // full types of the OUT arguments
+	list, err := splitTokens(argList)
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		arg, full, err := p.argument(a)
+		if err != nil {
+			return err
+		}
+		f.Arguments = append(f.Arguments, arg)
+		switch arg.Mode {
+		case "in", "variadic":
+			f.ArgumentTypes = append(f.ArgumentTypes, arg.Type)
+		case "inout":
+			f.ArgumentTypes = append(f.ArgumentTypes, arg.Type)
+			outs = append(outs, full)
+			f.ReturnType = arg.Type
+		case "out":
+			outs = append(outs, full)
+			f.ReturnType = arg.Type
+		}
+	}
+	switch {
+	case kind == "procedure":
+		f.ReturnType = "void"
+	case len(outs) == 1:
+		f.Result = outs[0]
+	case len(outs) > 1:
+		f.ReturnType, f.Result = "record", "record"
+	}
+	var rows bool
+	for !c.eof() {
+		switch {
+		case c.accept("returns", "table"):
+			cols, err := c.group()
+			if err != nil {
+				return err
+			}
+			var result []string
+			list, err := splitTokens(cols)
+			if err != nil {
+				return err
+			}
+			for _, col := range list {
+				cc := &cursor{ts: col}
+				name, err := cc.ident()
+				if err != nil {
+					return err
+				}
+				t, err := cc.typeName()
+				if err != nil {
+					return err
+				}
+				if !cc.eof() {
+					return cc.errorf("unexpected input")
+				}
+				a := Argument{Name: name, Mode: "table"}
+				var full string
+				a.Type, a.TypeName, full, a.Array = p.resolveType(t)
+				f.Arguments = append(f.Arguments, a)
+				f.ReturnType = a.Type
+				result = append(result, quoteIdent(name)+" "+full)
+			}
+			if len(result) > 1 {
+				f.ReturnType = "record"
+			}
+			f.ReturnsSet = true
+			f.Result = "TABLE(" + strings.Join(result, ", ") + ")"
+		case c.accept("returns"):
+			f.ReturnsSet = c.accept("setof")
+			t, err := c.typeName()
+			if err != nil {
+				return err
+			}
+			f.ReturnType, _, f.Result, _ = p.resolveType(t)
+			if f.ReturnsSet {
+				f.Result = "SETOF " + f.Result
+			}
+		case c.accept("language"):
+			lang, err := c.ident()
+			if err != nil {
+				return err
+			}
+			f.Language = strings.ToLower(lang)
+			if f.Language == "c" || f.Language == "internal" {
+				f.Cost = 1
+			}
+		case c.accept("immutable"), c.accept("stable"), c.accept("volatile"):
+			f.Volatility = c.ts[c.i-1].text
+		case c.accept("strict"), c.accept("returns", "null", "on", "null", "input"):
+			f.Strict = true
+		case c.accept("called", "on", "null", "input"):
+			f.Strict = false
+		case c.accept("security", "definer"), c.accept("external", "security", "definer"):
+			f.SecurityDefiner = true
+		case c.accept("security", "invoker"), c.accept("external", "security", "invoker"):
+			f.SecurityDefiner = false
+		case c.accept("leakproof"):
+			f.Leakproof = true
+		case c.accept("not", "leakproof"):
+			f.Leakproof = false
+		case c.accept("window"):
+			f.Kind = "window"
+		case c.accept("parallel"):
+			if f.Parallel, err = c.ident(); err != nil {
+				return err
+			}
+		case c.accept("cost"):
+			if f.Cost, err = c.float(); err != nil {
+				return err
+			}
+		case c.accept("rows"):
+			if f.Rows, err = c.float(); err != nil {
+				return err
+			}
+			rows = true
+		case c.accept("support"):
+			if _, err := p.qname(c); err != nil {
+				return err
+			}
+		case c.accept("set"):
+			name, err := c.ident()
+			if err != nil {
+				return err
+			}
+			if !c.accept("to") {
+				if err := c.expect("="); err != nil {
+					return err
+				}
+			}
+			var values []string
+			for {
+				t := c.next()
+				if t.typ == tokOp {
+					return c.errorf("expected a value")
+				}
+				values = append(values, t.text)
+				if !c.accept(",") {
+					break
+				}
+			}
+			f.Config = append(f.Config, name+"="+strings.Join(values, ", "))
+		case c.accept("as"):
+			var src []string
+			for {
+				t := c.next()
+				if t.typ != tokString {
+					return c.errorf("expected a string")
+				}
+				src = append(src, t.text)
+				if !c.accept(",") {
+					break
+				}
+			}
+			// for C functions: 'file', 'symbol'
+			f.Src = src[len(src)-1]
+		case c.accept("return"):
+			c.rest()
+		default:
+			return c.errorf("unsupported function option")
+		}
+	}
+	if f.ReturnsSet && !rows {
+		f.Rows = 1000
+	}
+	if f.Result == "" && f.ReturnType != "" && kind != "procedure" {
+		f.Result = f.ReturnType
+	}
+
+	if _, ok := p.local(q); ok {
+		if _, found := p.s.Functions[f.Signature()]; found && !replace {
+			return c.errorf("%s %q already exists with same argument types", kind, f.Name)
+		}
+		p.s.Functions[f.Signature()] = f
+	}
+	return nil
+}
+
+// argument reads a function argument. It also gives the full type.
+func (p *ddlParser) argument(ts []token) (Argument, string, error) {
+	a := Argument{Mode: "in"}
+	c := &cursor{ts: ts}
+	for _, m := range []string{"in", "out", "inout", "variadic"} {
+		if c.accept(m) {
+			a.Mode = m
+			break
+		}
+	}
+	typ := c.until(func(t token) bool { return t.is("default") || t.is("=") })
+	if c.accept("default") || c.accept("=") {
+		a.Default = joinTokens(c.rest())
+	}
+	// the name is optional, which we only know when the rest is a type
+	tc := &cursor{ts: typ}
+	t, err := tc.typeName()
+	if err != nil || !tc.eof() {
+		tc = &cursor{ts: typ}
+		if a.Name, err = tc.ident(); err != nil {
+			return a, "", err
+		}
+		if t, err = tc.typeName(); err != nil {
+			return a, "", err
+		}
+		if !tc.eof() {
+			return a, "", tc.errorf("unexpected input")
+		}
+	}
+	var full string
+	a.Type, a.TypeName, full, a.Array = p.resolveType(t)
+	return a, full, nil
+}
+
+// functionSignature reads "name" or "name(arguments)", and finds the
+// function. Without arguments the name has to be unique.
+func (p *ddlParser) functionSignature(c *cursor) (string, bool, error) {
+	q, err := p.qname(c)
+	if err != nil {
+		return "", false, err
+	}
+	name, ok := p.local(q)
+	if !c.at("(") {
+		sigs := p.s.FunctionsByName(name)
+		if !ok || len(sigs) == 0 {
+			return "", false, nil
+		}
+		if len(sigs) > 1 {
+			return "", false, c.errorf("function name %q is not unique", name)
+		}
+		return sigs[0], true, nil
+	}
+	args, err := c.group()
+	if err != nil {
+		return "", false, err
+	}
+	f := Function{Name: name}
+	list, err := splitTokens(args)
+	if err != nil {
+		return "", false, err
+	}
+	for _, a := range list {
+		arg, _, err := p.argument(a)
+		if err != nil {
+			return "", false, err
+		}
+		if arg.Mode != "out" {
+			f.ArgumentTypes = append(f.ArgumentTypes, arg.Type)
+		}
+	}
+	sig := f.Signature()
+	_, found := p.s.Functions[sig]
+	return sig, ok && found, nil
+}
+
+func (p *ddlParser) createType(c *cursor) error {
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	var t Type
+	switch {
+	case c.eof():
+		// a shell type
+		return nil
+	case c.accept("as", "enum"):
+		labels, err := c.group()
+		if err != nil {
+			return err
+		}
+		t.Type = "enum"
+		list, err := splitTokens(labels)
+		if err != nil {
+			return err
+		}
+		for _, l := range list {
+			if len(l) != 1 || l[0].typ != tokString {
+				return c.errorf("expected a label")
+			}
+			t.Labels = append(t.Labels, l[0].text)
+		}
+	case c.accept("as", "range"):
+		opts, err := c.group()
+		if err != nil {
+			return err
+		}
+		t.Type = "range"
+		list, err := splitTokens(opts)
+		if err != nil {
+			return err
+		}
+		for _, o := range list {
+			oc := &cursor{ts: o}
+			key, err := oc.ident()
+			if err != nil {
+				return err
+			}
+			if err := oc.expect("="); err != nil {
+				return err
+			}
+			switch key {
+			case "subtype":
+				st, err := oc.typeName()
+				if err != nil {
+					return err
+				}
+				t.Subtype, _, _, _ = p.resolveType(st)
+			case "subtype_opclass":
+				_, err = p.qname(oc)
+			default:
+				var v QName
+				if v, err = p.qname(oc); err != nil {
+					return err
+				}
+				switch key {
+				case "collation":
+					t.Collation = v.Name
+				case "canonical":
+					t.Canonical = v.String()
+				case "subtype_diff":
+					t.SubtypeDiff = v.String()
+				case "multirange_type_name":
+					t.Multirange = v.Name
+				default:
+					return oc.errorf("unsupported range option")
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if !oc.eof() {
+				return oc.errorf("unexpected input")
+			}
+		}
+		if t.Multirange == "" {
+			if i := strings.Index(q.Name, "range"); i >= 0 {
+				t.Multirange = q.Name[:i] + "multirange" + q.Name[i+len("range"):]
+			} else {
+				t.Multirange = q.Name + "_multirange"
+			}
+		}
+	case c.accept("as"):
+		attrs, err := c.group()
+		if err != nil {
+			return err
+		}
+		t.Type = "composite"
+		t.Attributes = map[string]Column{}
+		list, err := splitTokens(attrs)
+		if err != nil {
+			return err
+		}
+		for i, a := range list {
+			ac := &cursor{ts: a}
+			name, err := ac.ident()
+			if err != nil {
+				return err
+			}
+			at, err := ac.typeName()
+			if err != nil {
+				return err
+			}
+			if ac.accept("collate") {
+				if _, err := p.qname(ac); err != nil {
+					return err
+				}
+			}
+			if !ac.eof() {
+				return ac.errorf("unexpected input")
+			}
+			col := Column{Position: i + 1, AttNum: i + 1}
+			col.Type, col.TypeName, col.FullType, col.Array = p.resolveType(at)
+			t.Attributes[name] = col
+		}
+	default:
+		return c.errorf("unsupported type")
+	}
+	if name, ok := p.local(q); ok {
+		p.s.Types[name] = t
+	}
+	return nil
+}
+
+// domainEnd are the keywords which end an expression in CREATE DOMAIN
+var domainEnd = set("check", "collate", "constraint", "default", "not", "null")
+
+func (p *ddlParser) createDomain(c *cursor) error {
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	c.accept("as")
+	bt, err := c.typeName()
+	if err != nil {
+		return err
+	}
+	t := Type{
+		Type:        "domain",
+		Constraints: map[string]Constraint{},
+	}
+	_, _, t.BaseType, _ = p.resolveType(bt)
+	for !c.eof() {
+		var cname string
+		if c.accept("constraint") {
+			if cname, err = c.ident(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case c.accept("default"):
+			t.Default = joinTokens(c.until(func(t token) bool { return t.typ == tokIdent && domainEnd[t.text] }))
+		case c.accept("not", "null"):
+			t.NotNull = true
+		case c.accept("null"):
+			t.NotNull = false
+		case c.accept("collate"):
+			_, err = p.qname(c)
+		case c.accept("check"):
+			err = p.domainCheck(c, q.Name, &t, cname)
+		default:
+			return c.errorf("unsupported domain option")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if name, ok := p.local(q); ok {
+		p.s.Types[name] = t
+	}
+	return nil
+}
+
+// domainCheck reads the expression of a CHECK constraint of a domain,
+// with its optional NOT VALID.
+func (p *ddlParser) domainCheck(c *cursor, domain string, t *Type, name string) error {
+	expr, err := c.group()
+	if err != nil {
+		return err
+	}
+	con := Constraint{
+		Type:       "check",
+		Validated:  !c.accept("not", "valid"),
+		Definition: "CHECK (" + parens(joinTokens(expr)) + ")",
+	}
+	if !con.Validated {
+		con.Definition += " NOT VALID"
+	}
+	if name == "" {
+		name = domain + "_check"
+		for n := 1; t.Constraints[name].Type != ""; n++ {
+			name = fmt.Sprintf("%s_check%d", domain, n)
+		}
+	}
+	t.Constraints[name] = con
+	return nil
+}
+
+func (p *ddlParser) alterType(c *cursor) error {
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	name, ok := p.local(q)
+	if !ok {
+		c.rest()
+		return nil
+	}
+	t, ok := p.s.Types[name]
+	if !ok || t.Type == "domain" {
+		return c.errorf("type %q does not exist", name)
+	}
+	switch {
+	case c.accept("add", "value"):
+		ifNotExists := c.accept("if", "not", "exists")
+		label := c.next()
+		if label.typ != tokString {
+			return c.errorf("expected a label")
+		}
+		if hasString(t.Labels, label.text) {
+			if ifNotExists {
+				return nil
+			}
+			return c.errorf("enum label %q already exists", label.text)
+		}
+		pos := len(t.Labels)
+		if before := c.accept("before"); before || c.accept("after") {
+			other := c.next()
+			pos = -1
+			for i, l := range t.Labels {
+				if l == other.text {
+					pos = i
+				}
+			}
+			if pos < 0 {
+				return c.errorf("enum label %q does not exist", other.text)
+			}
+			if !before {
+				pos++
+			}
+		}
+		t.Labels = append(t.Labels[:pos:pos], append([]string{label.text}, t.Labels[pos:]...)...)
+	case c.accept("rename", "value"):
+		from := c.next()
+		if err := c.expectToken(from, tokString); err != nil {
+			return err
+		}
+		if err := c.expect("to"); err != nil {
+			return err
+		}
+		to := c.next()
+		if err := c.expectToken(to, tokString); err != nil {
+			return err
+		}
+		if !hasString(t.Labels, from.text) {
+			return c.errorf("enum label %q does not exist", from.text)
+		}
+		if hasString(t.Labels, to.text) {
+			return c.errorf("enum label %q already exists", to.text)
+		}
+		for i, l := range t.Labels {
+			if l == from.text {
+				t.Labels[i] = to.text
+			}
+		}
+	default:
+		list, err := splitTokens(c.rest())
+		if err != nil {
+			return err
+		}
+		for _, action := range list {
+			ac := &cursor{ts: action}
+			switch {
+			case ac.accept("add", "attribute"):
+				an, err := ac.ident()
+				if err != nil {
+					return err
+				}
+				at, err := ac.typeName()
+				if err != nil {
+					return err
+				}
+				col := Column{Position: len(t.Attributes) + 1}
+				for _, a := range t.Attributes {
+					if a.AttNum >= col.AttNum {
+						col.AttNum = a.AttNum + 1
+					}
+				}
+				col.Type, col.TypeName, col.FullType, col.Array = p.resolveType(at)
+				t.Attributes[an] = col
+			case ac.accept("drop", "attribute"):
+				ac.accept("if", "exists")
+				an, err := ac.ident()
+				if err != nil {
+					return err
+				}
+				delete(t.Attributes, an)
+				// positions don't have gaps
+				for i, n := range (&Relation{Columns: t.Attributes}).ColumnNames() {
+					a := t.Attributes[n]
+					a.Position = i + 1
+					t.Attributes[n] = a
+				}
+			case ac.accept("alter", "attribute"):
+				an, err := ac.ident()
+				if err != nil {
+					return err
+				}
+				ac.accept("set", "data")
+				if err := ac.expect("type"); err != nil {
+					return err
+				}
+				at, err := ac.typeName()
+				if err != nil {
+					return err
+				}
+				a, ok := t.Attributes[an]
+				if !ok {
+					return ac.errorf("attribute %q does not exist", an)
+				}
+				a.Type, a.TypeName, a.FullType, a.Array = p.resolveType(at)
+				t.Attributes[an] = a
+			default:
+				return ac.errorf("unsupported ALTER TYPE action")
+			}
+			if !ac.accept("cascade") {
+				ac.accept("restrict")
+			}
+			if !ac.eof() {
+				return ac.errorf("unexpected input")
+			}
+		}
+	}
+	p.s.Types[name] = t
+	return nil
+}
+
+func (p *ddlParser) alterDomain(c *cursor) error {
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	name, ok := p.local(q)
+	if !ok {
+		c.rest()
+		return nil
+	}
+	t, ok := p.s.Types[name]
+	if !ok || t.Type != "domain" {
+		return c.errorf("domain %q does not exist", name)
+	}
+	switch {
+	case c.accept("set", "default"):
+		t.Default = joinTokens(c.rest())
+	case c.accept("drop", "default"):
+		t.Default = ""
+	case c.accept("set", "not", "null"):
+		t.NotNull = true
+	case c.accept("drop", "not", "null"):
+		t.NotNull = false
+	case c.accept("add"):
+		var cname string
+		if c.accept("constraint") {
+			if cname, err = c.ident(); err != nil {
+				return err
+			}
+		}
+		if err := c.expect("check"); err != nil {
+			return err
+		}
+		return p.setType(name, t, p.domainCheck(c, name, &t, cname))
+	case c.accept("drop", "constraint"):
+		ifExists := c.accept("if", "exists")
+		cname, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if !c.accept("cascade") {
+			c.accept("restrict")
+		}
+		if _, ok := t.Constraints[cname]; !ok && !ifExists {
+			return c.errorf("constraint %q does not exist", cname)
+		}
+		delete(t.Constraints, cname)
+	case c.accept("validate", "constraint"):
+		cname, err := c.ident()
+		if err != nil {
+			return err
+		}
+		con := t.Constraints[cname]
+		con.Validated = true
+		con.Definition = strings.TrimSuffix(con.Definition, " NOT VALID")
+		t.Constraints[cname] = con
+	default:
+		return c.errorf("unsupported ALTER DOMAIN action")
+	}
+	p.s.Types[name] = t
+	return nil
+}
+
+// setType stores the type, unless there is an error.
+func (p *ddlParser) setType(name string, t Type, err error) error {
+	if err == nil {
+		p.s.Types[name] = t
+	}
+	return err
+}
+
+func (p *ddlParser) createTrigger(c *cursor, constraint bool) error {
+	name, err := c.ident()
+	if err != nil {
+		return err
+	}
+	t := Trigger{
+		Level:      "statement",
+		Enabled:    "origin",
+		Constraint: constraint,
+	}
+	switch {
+	case c.accept("before"):
+		t.Timing = "before"
+	case c.accept("after"):
+		t.Timing = "after"
+	case c.accept("instead", "of"):
+		t.Timing = "instead of"
+	default:
+		return c.errorf("expected BEFORE, AFTER, or INSTEAD OF")
+	}
+	for {
+		ev, err := c.ident()
+		if err != nil {
+			return err
+		}
+		switch ev {
+		case "insert", "delete", "truncate":
+		case "update":
+			if c.accept("of") {
+				for {
+					col, err := c.ident()
+					if err != nil {
+						return err
+					}
+					t.UpdateColumns = append(t.UpdateColumns, col)
+					if !c.accept(",") {
+						break
+					}
+				}
+			}
+		default:
+			return c.errorf("unsupported trigger event %q", ev)
+		}
+		t.Events = append(t.Events, ev)
+		if !c.accept("or") {
+			break
+		}
+	}
+	// we use the order of pg_trigger.tgtype
+	sort.Slice(t.Events, func(i, j int) bool {
+		return triggerEventOrder[t.Events[i]] < triggerEventOrder[t.Events[j]]
+	})
+	if err := c.expect("on"); err != nil {
+		return err
+	}
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	for !c.eof() {
+		switch {
+		case c.accept("from"):
+			_, err = p.qname(c)
+		case c.accept("deferrable"), c.accept("not", "deferrable"), c.accept("initially", "deferred"), c.accept("initially", "immediate"):
+		case c.accept("referencing"):
+			for {
+				var target *string
+				switch {
+				case c.accept("old", "table"):
+					target = &t.OldTable
+				case c.accept("new", "table"):
+					target = &t.NewTable
+				}
+				if target == nil {
+					break
+				}
+				c.accept("as")
+				if *target, err = c.ident(); err != nil {
+					return err
+				}
+			}
+		case c.accept("for"):
+			c.accept("each")
+			if c.accept("row") {
+				t.Level = "row"
+			} else if err = c.expect("statement"); err == nil {
+				t.Level = "statement"
+			}
+		case c.accept("when"):
+			var when []token
+			if when, err = c.group(); err == nil {
+				t.When = joinTokens(when)
+			}
+		case c.accept("execute"):
+			if !c.accept("function") {
+				if err := c.expect("procedure"); err != nil {
+					return err
+				}
+			}
+			var fn QName
+			if fn, err = p.qname(c); err != nil {
+				return err
+			}
+			t.Function = p.qualify(fn).String()
+			args, err := c.group()
+			if err != nil {
+				return err
+			}
+			list, err := splitTokens(args)
+			if err != nil {
+				return err
+			}
+			for _, a := range list {
+				if len(a) != 1 {
+					return c.errorf("unsupported trigger argument")
+				}
+				t.Arguments = append(t.Arguments, a[0].text)
+			}
+		default:
+			return c.errorf("unsupported trigger option")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	table, r, ok, err := p.relation(c, q)
+	if !ok {
+		return err
+	}
+	if r.Triggers == nil {
+		r.Triggers = map[string]Trigger{}
+	}
+	r.Triggers[name] = t
+	p.s.Relations[table] = r
+	return nil
+}
+
+var triggerEventOrder = map[string]int{
+	"insert":   1,
+	"update":   2,
+	"delete":   3,
+	"truncate": 4,
+}
+
+func (p *ddlParser) createPolicy(c *cursor) error {
+	name, err := c.ident()
+	if err != nil {
+		return err
+	}
+	if err := c.expect("on"); err != nil {
+		return err
+	}
+	q, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	pol := Policy{
+		Command:    "all",
+		Permissive: true,
+		Roles:      []string{"public"},
+	}
+	for !c.eof() {
+		switch {
+		case c.accept("as", "permissive"):
+			pol.Permissive = true
+		case c.accept("as", "restrictive"):
+			pol.Permissive = false
+		case c.accept("for"):
+			pol.Command, err = c.ident()
+		case c.accept("to"):
+			pol.Roles = nil
+			for {
+				var role string
+				if role, err = c.ident(); err != nil {
+					return err
+				}
+				pol.Roles = append(pol.Roles, role)
+				if !c.accept(",") {
+					break
+				}
+			}
+			sort.Strings(pol.Roles)
+		case c.accept("using"):
+			var expr []token
+			if expr, err = c.group(); err == nil {
+				pol.Using = joinTokens(expr)
+			}
+		case c.accept("with", "check"):
+			var expr []token
+			if expr, err = c.group(); err == nil {
+				pol.WithCheck = joinTokens(expr)
+			}
+		default:
+			return c.errorf("unsupported policy option")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	table, r, ok, err := p.relation(c, q)
+	if !ok {
+		return err
+	}
+	if r.Policies == nil {
+		r.Policies = map[string]Policy{}
+	}
+	r.Policies[name] = pol
+	p.s.Relations[table] = r
+	return nil
+}
+
+func (p *ddlParser) createEventTrigger(c *cursor) error {
+	name, err := c.ident()
+	if err != nil {
+		return err
+	}
+	if err := c.expect("on"); err != nil {
+		return err
+	}
+	e := EventTrigger{Enabled: "origin"}
+	if e.Event, err = c.ident(); err != nil {
+		return err
+	}
+	if c.accept("when") {
+		for {
+			if err := c.expect("tag", "in"); err != nil {
+				return err
+			}
+			tags, err := c.group()
+			if err != nil {
+				return err
+			}
+			list, err := splitTokens(tags)
+			if err != nil {
+				return err
+			}
+			for _, t := range list {
+				if len(t) != 1 || t[0].typ != tokString {
+					return c.errorf("expected a tag")
+				}
+				e.Tags = append(e.Tags, t[0].text)
+			}
+			if !c.accept("and") {
+				break
+			}
+		}
+	}
+	if err := c.expect("execute"); err != nil {
+		return err
+	}
+	if !c.accept("function") {
+		if err := c.expect("procedure"); err != nil {
+			return err
+		}
+	}
+	fn, err := p.qname(c)
+	if err != nil {
+		return err
+	}
+	e.Function = p.qualify(fn).String()
+	if err := c.expect("(", ")"); err != nil {
+		return err
+	}
+	p.s.EventTriggers[name] = e
+	return nil
+}
+
+func (p *ddlParser) alterEventTrigger(c *cursor) error {
+	name, err := c.ident()
+	if err != nil {
+		return err
+	}
+	e, ok := p.s.EventTriggers[name]
+	if !ok {
+		return c.errorf("event trigger %q does not exist", name)
+	}
+	switch {
+	case c.accept("enable", "replica"):
+		e.Enabled = "replica"
+	case c.accept("enable", "always"):
+		e.Enabled = "always"
+	case c.accept("enable"):
+		e.Enabled = "origin"
+	case c.accept("disable"):
+		e.Enabled = "disabled"
+	default:
+		return c.errorf("unsupported ALTER EVENT TRIGGER action")
+	}
+	p.s.EventTriggers[name] = e
+	return nil
+}
+
+func (p *ddlParser) drop(c *cursor) error {
+	var kind string
+	for _, k := range []string{
+		"table", "view", "materialized view", "index", "sequence", "type",
+		"domain", "function", "procedure", "routine", "trigger", "policy",
+		"event trigger", "schema", "extension",
+	} {
+		if c.accept(strings.Fields(k)...) {
+			kind = k
+			break
+		}
+	}
+	if kind == "" {
+		return c.errorf("unsupported statement")
+	}
+	if kind == "extension" {
+		c.rest()
+		return nil
+	}
+	c.accept("concurrently")
+	ifExists := c.accept("if", "exists")
+	if n := len(c.ts); c.ts[n-1].is("cascade") || c.ts[n-1].is("restrict") {
+		c.ts = c.ts[:n-1]
+	}
+	list, err := splitTokens(c.rest())
+	if err != nil {
+		return err
+	}
+	for _, obj := range list {
+		oc := &cursor{ts: obj}
+		found, err := p.dropObject(oc, kind)
+		if err != nil {
+			return err
+		}
+		if !oc.eof() {
+			return oc.errorf("unexpected input")
+		}
+		if !found && !ifExists {
+			return (&cursor{ts: obj}).errorf("%s %q does not exist", kind, joinTokens(obj))
+		}
+	}
+	return nil
+}
+
+// dropObject drops a single object, and tells if it was there. Objects in
+// other schemas are always there.
+func (p *ddlParser) dropObject(c *cursor, kind string) (bool, error) {
+	switch kind {
+	case "schema":
+		name, err := c.ident()
+		if name == p.s.Name {
+			p.reset(name)
+		}
+		return true, err
+	case "event trigger":
+		name, err := c.ident()
+		_, ok := p.s.EventTriggers[name]
+		delete(p.s.EventTriggers, name)
+		return ok, err
+	case "function", "procedure", "routine":
+		sig, ok, err := p.functionSignature(c)
+		delete(p.s.Functions, sig)
+		return ok, err
+	case "trigger", "policy":
+		name, err := c.ident()
+		if err != nil {
+			return false, err
+		}
+		if err := c.expect("on"); err != nil {
+			return false, err
+		}
+		q, err := p.qname(c)
+		if err != nil {
+			return false, err
+		}
+		table, ok := p.local(q)
+		if !ok {
+			return true, nil
+		}
+		r := p.s.Relations[table]
+		if kind == "trigger" {
+			_, ok = r.Triggers[name]
+			delete(r.Triggers, name)
+		} else {
+			_, ok = r.Policies[name]
+			delete(r.Policies, name)
+		}
+		return ok, nil
+	}
+
+	q, err := p.qname(c)
+	if err != nil {
+		return false, err
+	}
+	name, ok := p.local(q)
+	if !ok {
+		return true, nil
+	}
+	switch kind {
+	case "index":
+		_, ok = p.s.Indexes[name]
+		delete(p.s.Indexes, name)
+	case "sequence":
+		_, ok = p.s.Sequences[name]
+		delete(p.s.Sequences, name)
+	case "type", "domain":
+		_, ok = p.s.Types[name]
+		delete(p.s.Types, name)
+	default:
+		var r Relation
+		r, ok = p.s.Relations[name]
+		if ok && relationKind(r) != strings.ToUpper(kind) {
+			return false, c.errorf("%q is not a %s", name, kind)
+		}
+		p.dropRelation(name)
+	}
+	return ok, nil
+}
+
+func (p *ddlParser) comment(c *cursor) error {
+	var apply func(string)
+	switch {
+	case c.accept("schema"):
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if name == p.s.Name {
+			apply = func(s string) { p.s.Comment = s }
+		}
+	case c.accept("table"), c.accept("view"), c.accept("materialized", "view"):
+		q, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		name, r, ok, err := p.relation(c, q)
+		if err != nil {
+			return err
+		}
+		if ok {
+			apply = func(s string) {
+				r.Comment = s
+				p.s.Relations[name] = r
+			}
+		}
+	case c.accept("column"):
+		parts, err := c.dotted()
+		if err != nil {
+			return err
+		}
+		if len(parts) < 2 {
+			return c.errorf("expected table.column")
+		}
+		q := QName{Name: parts[len(parts)-2]}
+		if len(parts) > 2 {
+			q.Schema = parts[len(parts)-3]
+		}
+		col := parts[len(parts)-1]
+		name, _, ok, err := p.relation(c, q)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if _, found := p.s.Relations[name].Columns[col]; !found {
+				return c.errorf("column %q does not exist", col)
+			}
+			apply = func(s string) {
+				p.alterColumn(name, col, true, func(c *Column) { c.Comment = s })
+			}
+		}
+	case c.accept("index"), c.accept("sequence"), c.accept("type"), c.accept("domain"):
+		kind := c.ts[c.i-1].text
+		q, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		if name, ok := p.local(q); ok {
+			apply, err = p.commentOnObject(c, kind, name)
+			if err != nil {
+				return err
+			}
+		}
+	case c.accept("constraint"), c.accept("trigger"), c.accept("policy"):
+		kind := c.ts[c.i-1].text
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		if err := c.expect("on"); err != nil {
+			return err
+		}
+		domain := kind == "constraint" && c.accept("domain")
+		q, err := p.qname(c)
+		if err != nil {
+			return err
+		}
+		if domain {
+			apply, err = p.commentOnDomainConstraint(c, q, name)
+		} else {
+			apply, err = p.commentOnTableObject(c, kind, q, name)
+		}
+		if err != nil {
+			return err
+		}
+	case c.accept("function"), c.accept("procedure"), c.accept("routine"):
+		sig, ok, err := p.functionSignature(c)
+		if err != nil {
+			return err
+		}
+		if ok {
+			apply = func(s string) {
+				f := p.s.Functions[sig]
+				f.Comment = s
+				p.s.Functions[sig] = f
+			}
+		}
+	case c.accept("event", "trigger"):
+		name, err := c.ident()
+		if err != nil {
+			return err
+		}
+		e, ok := p.s.EventTriggers[name]
+		if !ok {
+			return c.errorf("event trigger %q does not exist", name)
+		}
+		apply = func(s string) {
+			e.Comment = s
+			p.s.EventTriggers[name] = e
+		}
+	default:
+		// not something we keep track of
+		c.until(func(t token) bool { return t.is("is") })
+	}
+	if err := c.expect("is"); err != nil {
+		return err
+	}
+	var comment string
+	if !c.accept("null") {
+		t := c.next()
+		if t.typ != tokString {
+			return c.errorf("expected a string")
+		}
+		comment = t.text
+	}
+	if apply != nil {
+		apply(comment)
+	}
+	return nil
+}
+
+func (p *ddlParser) commentOnObject(c *cursor, kind, name string) (func(string), error) {
+	switch kind {
+	case "index":
+		i, ok := p.s.Indexes[name]
+		if !ok {
+			return nil, c.errorf("index %q does not exist", name)
+		}
+		return func(s string) {
+			i.Comment = s
+			p.s.Indexes[name] = i
+		}, nil
+	case "sequence":
+		seq, ok := p.s.Sequences[name]
+		if !ok {
+			return nil, c.errorf("sequence %q does not exist", name)
+		}
+		return func(s string) {
+			seq.Comment = s
+			p.s.Sequences[name] = seq
+		}, nil
+	default:
+		t, ok := p.s.Types[name]
+		if !ok {
+			return nil, c.errorf("%s %q does not exist", kind, name)
+		}
+		return func(s string) {
+			t.Comment = s
+			p.s.Types[name] = t
+		}, nil
+	}
+}
+
+func (p *ddlParser) commentOnDomainConstraint(c *cursor, q QName, name string) (func(string), error) {
+	domain, ok := p.local(q)
+	if !ok {
+		return nil, nil
+	}
+	t := p.s.Types[domain]
+	con, ok := t.Constraints[name]
+	if !ok {
+		return nil, c.errorf("constraint %q does not exist", name)
+	}
+	return func(s string) {
+		con.Comment = s
+		t.Constraints[name] = con
+	}, nil
+}
+
+func (p *ddlParser) commentOnTableObject(c *cursor, kind string, q QName, name string) (func(string), error) {
+	table, r, ok, err := p.relation(c, q)
+	if !ok {
+		return nil, err
+	}
+	switch kind {
+	case "constraint":
+		con, ok := p.s.Constraints[table][name]
+		if !ok {
+			return nil, c.errorf("constraint %q does not exist", name)
+		}
+		return func(s string) {
+			con.Comment = s
+			p.s.Constraints[table][name] = con
+		}, nil
+	case "trigger":
+		t, ok := r.Triggers[name]
+		if !ok {
+			return nil, c.errorf("trigger %q does not exist", name)
+		}
+		return func(s string) {
+			t.Comment = s
+			r.Triggers[name] = t
+		}, nil
+	default:
+		pol, ok := r.Policies[name]
+		if !ok {
+			return nil, c.errorf("policy %q does not exist", name)
+		}
+		return func(s string) {
+			pol.Comment = s
+			r.Policies[name] = pol
+		}, nil
+	}
+}
+
+// finish fills in everything which depends on the whole schema: the lists,
+// positions, partitions and children, indexes of partitions, and foreign
+// key details.
+func (p *ddlParser) finish() *Schema {
+	s := p.s
+	s.Tables, s.Views, s.Materialized, s.Partitioned = nil, nil, nil, nil
+	for n, r := range s.Relations {
+		switch r.Type {
+		case "table":
+			s.Tables = append(s.Tables, n)
+		case "view":
+			s.Views = append(s.Views, n)
+		case "materialized view":
+			s.Materialized = append(s.Materialized, n)
+		case "partitioned table":
+			s.Partitioned = append(s.Partitioned, n)
+		}
+		r.Children, r.Partitions, r.DefaultPartition = nil, nil, QName{}
+		r.Indexes, r.Constraints, r.ConcurrentRefreshIndexes = nil, nil, nil
+		for i, c := range attNumOrder(r.Columns) {
+			col := r.Columns[c]
+			col.Position = i + 1
+			r.Columns[c] = col
+		}
+		s.Relations[n] = r
+	}
+	sort.Strings(s.Tables)
+	sort.Strings(s.Views)
+	sort.Strings(s.Materialized)
+	sort.Strings(s.Partitioned)
+
+	// children and partitions, from both schemas
+	all := map[QName]Relation{}
+	for q, r := range p.others {
+		all[q] = r
+	}
+	for n, r := range s.Relations {
+		all[QName{s.Name, n}] = r
+	}
+	for q, r := range all {
+		for _, parent := range r.Inherits {
+			p.relink(parent, func(pr *Relation) {
+				pr.Children = append(pr.Children, q)
+				sortQNames(pr.Children)
+			})
+		}
+		p.relink(r.PartitionOf, func(pr *Relation) {
+			pr.Partitions = append(pr.Partitions, q)
+			sortQNames(pr.Partitions)
+			if r.PartitionBound == "DEFAULT" {
+				pr.DefaultPartition = q
+			}
+		})
+	}
+
+	p.partitionIndexes()
+
+	for n, i := range s.Indexes {
+		r := s.Relations[i.Table]
+		r.Indexes = append(r.Indexes, n)
+		sort.Strings(r.Indexes)
+		if r.Type == "materialized view" && i.Unique && i.Predicate == "" && !hasExpression(i) {
+			r.ConcurrentRefreshIndexes = append(r.ConcurrentRefreshIndexes, n)
+			sort.Strings(r.ConcurrentRefreshIndexes)
+		}
+		s.Relations[i.Table] = r
+	}
+	for table, cs := range s.Constraints {
+		if len(cs) == 0 {
+			delete(s.Constraints, table)
+			continue
+		}
+		r := s.Relations[table]
+		for n, c := range cs {
+			if c.Type == "foreign key" {
+				cs[n] = p.foreignKey(c)
+			}
+			r.Constraints = append(r.Constraints, n)
+		}
+		sort.Strings(r.Constraints)
+		s.Relations[table] = r
+	}
+	return s
+}
+
+// relink changes one of our relations, if q is one.
+func (p *ddlParser) relink(q QName, f func(*Relation)) {
+	if q.Schema != p.s.Name {
+		return
+	}
+	r, ok := p.s.Relations[q.Name]
+	if !ok {
+		return
+	}
+	f(&r)
+	p.s.Relations[q.Name] = r
+}
+
+// partitionIndexes gives partitions the indexes of their partitioned table,
+// the way PostgreSQL does: an equal index is used when there is one,
+// otherwise one is made. Primary key and unique constraints are not
+// copied.
+func (p *ddlParser) partitionIndexes() {
+	s := p.s
+	for _, table := range dependencyOrder(sortedKeys(s.Relations), relationDeps(s)) {
+		r := s.Relations[table]
+		if r.Type != "partitioned table" {
+			continue
+		}
+		for _, n := range sortedKeys(s.Indexes) {
+			idx := s.Indexes[n]
+			if idx.Table != table || idx.Constraint != "" {
+				continue
+			}
+			parent := QName{s.Name, n}
+		partitions:
+			for _, part := range r.Partitions {
+				if part.Schema != s.Name {
+					continue
+				}
+				child := idx
+				child.Table = part.Name
+				child.PartitionOf = parent
+				child.Clustered = false
+				child.ReplicaIdentity = false
+				child.Comment = ""
+				for cn, ci := range s.Indexes {
+					if ci.Table == part.Name && ci.PartitionOf == parent {
+						continue partitions
+					}
+					if ci.Table == part.Name && ci.PartitionOf.Name == "" && sameIndex(ci, child) {
+						ci.PartitionOf = parent
+						s.Indexes[cn] = ci
+						continue partitions
+					}
+				}
+				var cols []string
+				for _, k := range idx.Keys {
+					cols = append(cols, indexColumnName(k))
+				}
+				p.addIndex(p.uniqueName(part.Name, cols, "idx"), child)
+			}
+		}
+	}
+}
+
+// sameIndex is true if the indexes do the same.
+func sameIndex(a, b Index) bool {
+	a.Definition, b.Definition = "", ""
+	a.Comment, b.Comment = "", ""
+	a.Clustered, b.Clustered = false, false
+	a.ReplicaIdentity, b.ReplicaIdentity = false, false
+	a.Partitioned, b.Partitioned = false, false
+	a.PartitionOf, b.PartitionOf = QName{}, QName{}
+	return reflect.DeepEqual(a, b)
+}
+
+func hasExpression(i Index) bool {
+	for _, k := range i.Keys {
+		if k.Column == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKey fills in the referenced columns and index of a foreign key to
+// one of our tables, and the Definition.
+func (p *ddlParser) foreignKey(c Constraint) Constraint {
+	if c.RefTable.Schema == p.s.Name {
+		var pk, match string
+		for _, n := range sortedKeys(p.s.Indexes) {
+			i := p.s.Indexes[n]
+			if i.Table != c.RefTable.Name || !i.Unique || i.Predicate != "" || hasExpression(i) {
+				continue
+			}
+			if i.Primary {
+				pk = n
+			}
+			if len(c.RefColumns) > 0 && equalStrings(i.Columns, c.RefColumns) && (match == "" || i.Primary) {
+				match = n
+			}
+		}
+		if len(c.RefColumns) == 0 && pk != "" {
+			c.RefColumns = p.s.Indexes[pk].Columns
+			match = pk
+		}
+		c.Index = match
+	}
+	if c.Definition == "" {
+		c.Definition = constraintDef(c)
+	}
+	return c
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// attNumOrder gives the column names in the order they were added.
+func attNumOrder(cols map[string]Column) []string {
+	names := sortedKeys(cols)
+	sort.SliceStable(names, func(i, j int) bool {
+		return cols[names[i]].AttNum < cols[names[j]].AttNum
+	})
+	return names
+}
+
+// typeSpec is a type as written
+type typeSpec struct {
+	name QName
+	// mods are the type modifiers, without spaces, such as "10,2"
+	mods string
+	// array is the number of dimensions
+	array int
+	// fields are the fields of an interval, such as "year to month"
+	fields string
+}
+
+// multiWordTypes are the built-in types with a name of more than one word.
+var multiWordTypes = []string{
+	"double precision",
+	"character varying",
+	"char varying",
+	"bit varying",
+}
+
+// typeName reads a type, such as "integer", "numeric(10, 2)", "timestamp(3)
+// with time zone", "other.mood", or "text[]".
+func (c *cursor) typeName() (typeSpec, error) {
+	var t typeSpec
+	for _, m := range multiWordTypes {
+		if c.accept(strings.Fields(m)...) {
+			t.name.Name = m
+			break
+		}
+	}
+	if t.name.Name == "" {
+		c.accept("setof")
+		q, err := c.qname()
+		if err != nil {
+			return t, err
+		}
+		t.name = q
+	}
+	at := *c
+	if c.at("(") {
+		mods, err := c.group()
+		if err != nil {
+			return t, err
+		}
+		t.mods = strings.ReplaceAll(joinTokens(mods), " ", "")
+	}
+	if t.name.Name == "float" && t.mods != "" && (t.name.Schema == "" || t.name.Schema == "pg_catalog") {
+		// float(p) is real or double precision, as in PostgreSQL
+		switch n, _ := strconv.Atoi(t.mods); {
+		case n >= 1 && n <= 24:
+			t.name.Name = "real"
+		case n >= 25 && n <= 53:
+			t.name.Name = "double precision"
+		default:
+			return t, at.errorf("precision for type float must be between 1 and 53")
+		}
+		t.mods = ""
+	}
+	if t.name.Name == "interval" && t.mods == "" {
+		fields, err := intervalFields(c)
+		if err != nil {
+			return t, err
+		}
+		t.fields = fields
+	}
+	if c.accept("with", "time", "zone") {
+		t.name.Name += " with time zone"
+	} else if c.accept("without", "time", "zone") {
+		t.name.Name += " without time zone"
+	}
+	for {
+		switch {
+		case c.at("["):
+			if _, err := c.group(); err != nil {
+				return t, err
+			}
+		case c.accept("array"):
+			if c.at("[") {
+				if _, err := c.group(); err != nil {
+					return t, err
+				}
+			}
+		default:
+			return t, nil
+		}
+		t.array++
+	}
+}
+
+// intervalFields reads the optional fields of an interval, such as "year to
+// month", or "second(3)", which can have a precision.
+func intervalFields(c *cursor) (string, error) {
+	var fields string
+	for _, f := range [][]string{
+		{"year", "to", "month"},
+		{"day", "to", "hour"},
+		{"day", "to", "minute"},
+		{"day", "to", "second"},
+		{"hour", "to", "minute"},
+		{"hour", "to", "second"},
+		{"minute", "to", "second"},
+		{"year"},
+		{"month"},
+		{"day"},
+		{"hour"},
+		{"minute"},
+		{"second"},
+	} {
+		if c.accept(f...) {
+			fields = strings.Join(f, " ")
+			break
+		}
+	}
+	if strings.HasSuffix(fields, "second") && c.at("(") {
+		p, err := c.group()
+		if err != nil {
+			return "", err
+		}
+		fields += "(" + joinTokens(p) + ")"
+	}
+	return fields, nil
+}
+
+// builtinType has the short and the full name of a type in pg_catalog
+type builtinType struct {
+	short, full string
+}
+
+var builtinTypes = map[string]builtinType{}
+
+func init() {
+	for short, aliases := range map[string][]string{
+		"int2":        {"smallint"},
+		"int4":        {"integer", "int"},
+		"int8":        {"bigint"},
+		"float4":      {"real"},
+		"float8":      {"double precision", "float"},
+		"numeric":     {"numeric", "decimal"},
+		"bool":        {"boolean"},
+		"varchar":     {"character varying", "char varying"},
+		"bpchar":      {"character", "char"},
+		"varbit":      {"bit varying"},
+		"timestamp":   {"timestamp without time zone", "timestamp"},
+		"timestamptz": {"timestamp with time zone"},
+		"time":        {"time without time zone", "time"},
+		"timetz":      {"time with time zone"},
+	} {
+		for _, a := range aliases {
+			builtinTypes[a] = builtinType{short, aliases[0]}
+		}
+		builtinTypes[short] = builtinType{short, aliases[0]}
+	}
+	for _, t := range strings.Fields(`
+		bit box bytea cidr circle cstring date daterange event_trigger
+		anyarray anyelement anyenum anynonarray anyrange inet int4range
+		int8range internal interval json jsonb line lseg macaddr macaddr8
+		money name numrange oid path pg_lsn point polygon record regclass
+		regproc regprocedure regtype text trigger tsquery tsrange tstzrange
+		tsvector uuid void xml
+	`) {
+		builtinTypes[t] = builtinType{t, t}
+	}
+}
+
+// resolveType gives the short name, the qualified name, the full name, and
+// whether it's an array type, as they are in a Column.
+func (p *ddlParser) resolveType(t typeSpec) (string, QName, string, bool) {
+	var (
+		short, full string
+		schema      string
+	)
+	if b, ok := builtinTypes[t.name.Name]; ok && (t.name.Schema == "" || t.name.Schema == "pg_catalog") {
+		short, full, schema = b.short, b.full, "pg_catalog"
+		if t.fields != "" {
+			full += " " + t.fields
+		}
+		mods := t.mods
+		if mods == "" && (short == "bpchar" || short == "bit") {
+			mods = "1"
+		}
+		if mods != "" {
+			if i := strings.Index(full, " "); i > 0 && strings.HasPrefix(short, "time") {
+				full = full[:i] + "(" + mods + ")" + full[i:]
+			} else {
+				full += "(" + mods + ")"
+			}
+		}
+	} else {
+		q := p.qualify(t.name)
+		short, schema = q.Name, q.Schema
+		full = q.String()
+		if q.Schema == "public" {
+			full = quoteIdent(q.Name)
+		}
+		if t.mods != "" {
+			full += "(" + t.mods + ")"
+		}
+	}
+	q := QName{schema, short}
+	if t.array > 0 {
+		// PostgreSQL doesn't keep track of the dimensions
+		short += "[]"
+		full += "[]"
+	}
+	return short, q, full, t.array > 0
+}
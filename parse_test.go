@@ -0,0 +1,405 @@
+package schemaspy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDDL(t *testing.T) {
+	s, err := ParseDDL("shop", `
+-- comments are fine
+CREATE SCHEMA shop;
+SET search_path TO shop;
+
+CREATE TABLE items (
+    id serial PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    price numeric(10, 2) CHECK (price > 0),
+    tags text[],
+    gone int
+);
+ALTER TABLE items DROP COLUMN gone;
+CREATE UNIQUE INDEX items_lower ON items (lower(name)) WHERE price IS NOT NULL;
+COMMENT ON COLUMN items.name IS 'what''s it called';
+
+CREATE TABLE orders (
+    id bigint GENERATED ALWAYS AS IDENTITY,
+    item_id int REFERENCES items ON DELETE CASCADE,
+    at timestamp(3) with time zone DEFAULT now()
+) PARTITION BY RANGE (at);
+CREATE TABLE orders_2020 PARTITION OF orders FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');
+CREATE INDEX ON orders (at);
+
+CREATE FUNCTION double(i int) RETURNS int AS $$ SELECT i * 2 $$ LANGUAGE SQL IMMUTABLE;
+GRANT SELECT ON items TO public;
+INSERT INTO items (name) VALUES ('ignored; really');
+CREATE TABLE other.elsewhere (id int);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := s.Tables, []string{"items", "orders_2020"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Partitioned, []string{"orders"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	items := s.Relations["items"]
+	if have, want := items.ColumnNames(), []string{"id", "name", "price", "tags"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["id"], (Column{
+		Type:     "int4",
+		TypeName: QName{"pg_catalog", "int4"},
+		FullType: "integer",
+		NotNull:  true,
+		Position: 1,
+		AttNum:   1,
+		Default:  "nextval('shop.items_id_seq'::regclass)",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["name"].FullType, "character varying(100)"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["name"].Comment, "what's it called"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["price"].FullType, "numeric(10,2)"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["tags"].TypeName, (QName{"pg_catalog", "text"}); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Columns["tags"].Array, true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := items.Constraints, []string{"items_pkey", "items_price_check"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Constraints["items"]["items_price_check"].Definition, "CHECK ((price > 0))"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Indexes["items_lower"].Keys, []IndexKey{{Expression: "lower(name)"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Indexes["items_lower"].Predicate, "(price IS NOT NULL)"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if have, want := s.Constraints["orders"]["orders_item_id_fkey"], (Constraint{
+		Type:       "foreign key",
+		Table:      "orders",
+		Columns:    []string{"item_id"},
+		Index:      "items_pkey",
+		RefTable:   QName{"shop", "items"},
+		RefColumns: []string{"id"},
+		OnUpdate:   "no action",
+		OnDelete:   "cascade",
+		Validated:  true,
+		Definition: "FOREIGN KEY (item_id) REFERENCES shop.items(id) ON DELETE CASCADE",
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	orders := s.Relations["orders"]
+	if have, want := orders.Columns["at"].FullType, "timestamp(3) with time zone"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := orders.Partitions, []QName{{"shop", "orders_2020"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Relations["orders_2020"].PartitionBound, "FOR VALUES FROM ('2020-01-01') TO ('2021-01-01')"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Indexes["orders_2020_at_idx"].PartitionOf, (QName{"shop", "orders_at_idx"}); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := sortedKeys(s.Sequences), []string{"items_id_seq", "orders_id_seq"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Sequences["orders_id_seq"].MaxValue, 1<<63-1; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	f := s.Functions["double(int4)"]
+	if have, want := f.Src, " SELECT i * 2 "; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := f.Volatility, "immutable"; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	for sql, want := range map[string]string{
+		"CREATE TABLE t (id int);\nCREATE TABLE t (id int)":     `line 2: relation "t" already exists, at end of statement`,
+		"CREATE TABLE t (id int) WHAT":                          `line 1: unexpected input, at "WHAT"`,
+		"CREATE TABLE t (id int);\nALTER TABLE t RENAME TO u":   `line 2: unsupported ALTER TABLE action, at "RENAME"`,
+		"CREATE RULE r AS ON INSERT TO t DO NOTHING":            `line 1: unsupported statement, at "RULE"`,
+		"CREATE TABLE t (id int, CHECK (id > 0)":                `line 1: unbalanced parenthesis, at "("`,
+		"SET search_path = '';\nCREATE TABLE t (id int)":        `line 2: no schema has been selected for "t", at "("`,
+		"CREATE TABLE u (id int);\nCREATE TABLE t (LIKE u)":     `line 2: unsupported table element, at "LIKE"`,
+		"CREATE TABLE t (id int PRIMARY KEY, PRIMARY KEY (id))": `line 1: multiple primary keys for table "t" are not allowed, at end of statement`,
+		"CREATE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE SQL;\nCREATE FUNCTION f() RETURNS int AS 'SELECT 2' LANGUAGE SQL": `line 2: function "f" already exists with same argument types, at end of statement`,
+		"CREATE TYPE m AS ENUM ('a');\nALTER TYPE m RENAME VALUE 'b' TO 'c'":                                                      `line 2: enum label "b" does not exist, at end of statement`,
+		"CREATE TABLE t (a int,)":          `line 1: expected a list element, at ","`,
+		"CREATE TABLE t (a int,\n, b int)": `line 2: expected a list element, at ","`,
+		"DROP TABLE a.b,":                  `line 1: expected a list element, at ","`,
+		"CREATE TABLE \"\" (a int)":        `line 1: zero-length delimited identifier`,
+		"CREATE VIEW v AS":                 `line 1: expected a query, at end of statement`,
+		"CREATE VIEW v AS DROP TABLE t":    `line 1: expected a query, at "DROP"`,
+		"CREATE TABLE t (a float(54))":     `line 1: precision for type float must be between 1 and 53, at "("`,
+		"CREATE TABLE t (id int);\nCREATE INDEX i ON t (id);\nALTER INDEX i RENAME TO j": `line 3: unsupported ALTER INDEX action, at "RENAME"`,
+		"DROP INDEX nosuch": `line 1: index "nosuch" does not exist, at "nosuch"`,
+	} {
+		_, err := ParseDDL("", sql)
+		if err == nil {
+			t.Errorf("%q: no error", sql)
+			continue
+		}
+		if have := err.Error(); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestParseDDLSearchPath(t *testing.T) {
+	s, err := ParseDDL("app", "CREATE TABLE t (a int)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := s.Tables, []string{"t"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	s, err = ParseDDL("app", `
+SET search_path = '';
+CREATE TABLE app.t (a int);
+CREATE TABLE other.t (a int);
+SET search_path TO other, app;
+CREATE TABLE u (a int);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := s.Tables, []string{"t"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestParseDDLFiles(t *testing.T) {
+	s, err := ParseDDLFiles("schemaspyint", "./test_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(s.Relations), 22; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := s.Relations["root"].Children, []QName{{"schemaspyint", "root_123"}, {"schemaspyint", "root_456"}, {"schemaspyint_other", "elsewhere"}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// what we write, we can read back
+	again, err := ParseDDL("schemaspyint", s.DDL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := Diff(s, again); len(cs) != 0 {
+		t.Errorf("round trip changes:\n%s", cs)
+	}
+
+	_, err = ParseDDLFiles("", "./nosuch.sql")
+	if err == nil || !strings.Contains(err.Error(), "nosuch.sql") {
+		t.Errorf("have %v", err)
+	}
+}
+
+func TestParseStatements(t *testing.T) {
+	const setup = `
+CREATE TABLE t (id int, v text);
+CREATE INDEX t_id ON t (id);
+CREATE INDEX t_v ON t (v);
+CREATE TABLE p (at date) PARTITION BY RANGE (at);
+CREATE TABLE p1 (at date);
+CREATE TABLE p2 PARTITION OF p FOR VALUES FROM ('2019-01-01') TO ('2020-01-01');
+CREATE TYPE mood AS ENUM ('sad', 'happy');
+CREATE TYPE pair AS (a int, b int);
+CREATE DOMAIN pos AS int CHECK (VALUE > 0);
+CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE FUNCTION et() RETURNS event_trigger AS $$ BEGIN END $$ LANGUAGE plpgsql;
+CREATE TRIGGER old BEFORE DELETE ON t FOR EACH ROW EXECUTE FUNCTION f();
+CREATE POLICY old ON t USING (true);
+CREATE EVENT TRIGGER old ON sql_drop EXECUTE FUNCTION et();
+`
+	for _, c := range []struct {
+		sql  string
+		have func(*Schema) interface{}
+		want interface{}
+	}{
+		{
+			"CREATE OR REPLACE FUNCTION f() RETURNS trigger AS $$ BEGIN RETURN OLD; END $$ LANGUAGE plpgsql",
+			func(s *Schema) interface{} { return s.Functions["f()"].Src },
+			" BEGIN RETURN OLD; END ",
+		},
+		// ALTER TYPE
+		{
+			"ALTER TYPE mood ADD VALUE 'meh' BEFORE 'sad'",
+			func(s *Schema) interface{} { return s.Types["mood"].Labels },
+			[]string{"meh", "sad", "happy"},
+		},
+		{
+			"ALTER TYPE mood ADD VALUE 'glad' AFTER 'sad'",
+			func(s *Schema) interface{} { return s.Types["mood"].Labels },
+			[]string{"sad", "glad", "happy"},
+		},
+		{
+			"ALTER TYPE mood ADD VALUE IF NOT EXISTS 'sad'",
+			func(s *Schema) interface{} { return s.Types["mood"].Labels },
+			[]string{"sad", "happy"},
+		},
+		{
+			"ALTER TYPE mood RENAME VALUE 'sad' TO 'blue'",
+			func(s *Schema) interface{} { return s.Types["mood"].Labels },
+			[]string{"blue", "happy"},
+		},
+		{
+			"ALTER TYPE pair ADD ATTRIBUTE c text, DROP ATTRIBUTE a",
+			func(s *Schema) interface{} {
+				a := s.Types["pair"].Attributes
+				return []interface{}{a["b"].Position, a["c"].Position, a["c"].AttNum, a["c"].FullType}
+			},
+			[]interface{}{1, 2, 3, "text"},
+		},
+		{
+			"ALTER TYPE pair ALTER ATTRIBUTE b SET DATA TYPE bigint",
+			func(s *Schema) interface{} { return s.Types["pair"].Attributes["b"].FullType },
+			"bigint",
+		},
+		// ALTER DOMAIN
+		{
+			"ALTER DOMAIN pos SET DEFAULT 1",
+			func(s *Schema) interface{} { return s.Types["pos"].Default },
+			"1",
+		},
+		{
+			"ALTER DOMAIN pos SET NOT NULL",
+			func(s *Schema) interface{} { return s.Types["pos"].NotNull },
+			true,
+		},
+		{
+			"ALTER DOMAIN pos ADD CONSTRAINT small CHECK (VALUE < 10) NOT VALID",
+			func(s *Schema) interface{} { return s.Types["pos"].Constraints["small"].Definition },
+			"CHECK ((VALUE < 10)) NOT VALID",
+		},
+		{
+			"ALTER DOMAIN pos DROP CONSTRAINT pos_check",
+			func(s *Schema) interface{} { return len(s.Types["pos"].Constraints) },
+			0,
+		},
+		// types
+		{
+			"ALTER TABLE t ADD COLUMN f float(24), ADD COLUMN g float(25), ADD COLUMN h interval year to month, ADD COLUMN i interval day to second(3), ADD COLUMN j interval(2)",
+			func(s *Schema) interface{} {
+				var types []string
+				for _, c := range []string{"f", "g", "h", "i", "j"} {
+					col := s.Relations["t"].Columns[c]
+					types = append(types, col.Type+" "+col.FullType)
+				}
+				return types
+			},
+			[]string{
+				"float4 real",
+				"float8 double precision",
+				"interval interval year to month",
+				"interval interval day to second(3)",
+				"interval interval(2)",
+			},
+		},
+		// triggers
+		{
+			"CREATE TRIGGER tr AFTER INSERT OR UPDATE OF v ON t FOR EACH ROW WHEN (NEW.id > 0) EXECUTE FUNCTION f()",
+			func(s *Schema) interface{} { return s.Relations["t"].Triggers["tr"] },
+			Trigger{
+				Timing:        "after",
+				Events:        []string{"insert", "update"},
+				UpdateColumns: []string{"v"},
+				Level:         "row",
+				When:          "NEW.id > 0",
+				Enabled:       "origin",
+				Function:      "public.f",
+			},
+		},
+		{
+			"ALTER TABLE t DISABLE TRIGGER old",
+			func(s *Schema) interface{} { return s.Relations["t"].Triggers["old"].Enabled },
+			"disabled",
+		},
+		{
+			"DROP TRIGGER old ON t",
+			func(s *Schema) interface{} { return len(s.Relations["t"].Triggers) },
+			0,
+		},
+		// policies
+		{
+			"CREATE POLICY pol ON t AS RESTRICTIVE FOR UPDATE TO bob USING (id > 0) WITH CHECK (id < 10)",
+			func(s *Schema) interface{} { return s.Relations["t"].Policies["pol"] },
+			Policy{Command: "update", Roles: []string{"bob"}, Using: "id > 0", WithCheck: "id < 10"},
+		},
+		{
+			"DROP POLICY old ON t",
+			func(s *Schema) interface{} { return len(s.Relations["t"].Policies) },
+			0,
+		},
+		// event triggers
+		{
+			"CREATE EVENT TRIGGER e ON ddl_command_start WHEN TAG IN ('CREATE TABLE') EXECUTE FUNCTION et()",
+			func(s *Schema) interface{} { return s.EventTriggers["e"] },
+			EventTrigger{Event: "ddl_command_start", Tags: []string{"CREATE TABLE"}, Function: "public.et", Enabled: "origin"},
+		},
+		{
+			"ALTER EVENT TRIGGER old DISABLE",
+			func(s *Schema) interface{} { return s.EventTriggers["old"].Enabled },
+			"disabled",
+		},
+		{
+			"DROP EVENT TRIGGER old",
+			func(s *Schema) interface{} { return len(s.EventTriggers) },
+			0,
+		},
+		// CLUSTER
+		{
+			"CLUSTER t USING t_id",
+			func(s *Schema) interface{} { return []bool{s.Indexes["t_id"].Clustered, s.Indexes["t_v"].Clustered} },
+			[]bool{true, false},
+		},
+		{
+			"CLUSTER t USING t_id; ALTER TABLE t CLUSTER ON t_v",
+			func(s *Schema) interface{} { return []bool{s.Indexes["t_id"].Clustered, s.Indexes["t_v"].Clustered} },
+			[]bool{false, true},
+		},
+		// ATTACH and DETACH
+		{
+			"ALTER TABLE p ATTACH PARTITION p1 FOR VALUES FROM ('2020-01-01') TO ('2021-01-01')",
+			func(s *Schema) interface{} {
+				return []interface{}{s.Relations["p1"].PartitionOf, s.Relations["p1"].PartitionBound, s.Relations["p"].Partitions}
+			},
+			[]interface{}{QName{"public", "p"}, "FOR VALUES FROM ('2020-01-01') TO ('2021-01-01')", []QName{{"public", "p1"}, {"public", "p2"}}},
+		},
+		{
+			"ALTER TABLE p DETACH PARTITION p2",
+			func(s *Schema) interface{} {
+				return []interface{}{s.Relations["p2"].PartitionOf, s.Relations["p2"].PartitionBound, len(s.Relations["p"].Partitions)}
+			},
+			[]interface{}{QName{}, "", 0},
+		},
+	} {
+		s, err := ParseDDL("public", setup+c.sql)
+		if err != nil {
+			t.Errorf("%s: %s", c.sql, err)
+			continue
+		}
+		if have := c.have(s); !reflect.DeepEqual(have, c.want) {
+			t.Errorf("%s: have %#v, want %#v", c.sql, have, c.want)
+		}
+	}
+}
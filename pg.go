@@ -1,26 +1,39 @@
 package schemaspy
 
 import (
-	"fmt"
-
-	"github.com/jackc/pgx"
+	"context"
 )
 
-// queryer is either a ConnPool, a Conn, or a Tx.
-type queryer interface {
-	Query(sql string, args ...interface{}) (*pgx.Rows, error)
+// pgVersion gives the server version as a number, such as 150004
+func pgVersion(ctx context.Context, conn Queryer) (int, error) {
+	rows, err := conn.Query(ctx, `SELECT current_setting('server_version_num')::int4`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var v int
+	for rows.Next() {
+		if err := rows.Scan(&v); err != nil {
+			return 0, err
+		}
+	}
+	return v, rows.Err()
 }
 
 type schemaNamespace struct {
-	OID     pgx.Oid
-	NspName string
+	OID      objectID
+	NspName  string
+	NspOwner string
+	NspACL   []string
 }
 
 // map with the namespace(schema) as key
-func pgNamespace(conn queryer) (map[string]schemaNamespace, error) {
-	rows, err := conn.Query(`
+func pgNamespace(ctx context.Context, conn Queryer) (map[string]schemaNamespace, error) {
+	rows, err := conn.Query(ctx, `
 		SELECT
-			oid, nspname
+			oid, nspname, pg_catalog.pg_get_userbyid(nspowner)::text,
+			to_json(COALESCE(nspacl, acldefault('n', nspowner))::text[])::text
 		FROM
 			pg_catalog.pg_namespace
 	`)
@@ -32,7 +45,7 @@ func pgNamespace(conn queryer) (map[string]schemaNamespace, error) {
 	var res = map[string]schemaNamespace{}
 	for rows.Next() {
 		var c schemaNamespace
-		if err := rows.Scan(&c.OID, &c.NspName); err != nil {
+		if err := rows.Scan(&c.OID, &c.NspName, &c.NspOwner, asJSON(&c.NspACL)); err != nil {
 			return nil, err
 		}
 		res[c.NspName] = c
@@ -40,36 +53,99 @@ func pgNamespace(conn queryer) (map[string]schemaNamespace, error) {
 	return res, rows.Err()
 }
 
+// names of all relations, in every namespace
+type schemaClassName struct {
+	RelNamespace   objectID
+	RelName        string
+	RelKind        string
+	RelIsPartition bool
+}
+
+func pgClassName(ctx context.Context, conn Queryer) (map[objectID]schemaClassName, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				oid, relnamespace, relname, relkind::text, relispartition
+			FROM
+				pg_catalog.pg_class
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaClassName{}
+	for rows.Next() {
+		var (
+			c   schemaClassName
+			oid objectID
+		)
+		if err := rows.Scan(&oid, &c.RelNamespace, &c.RelName, &c.RelKind, &c.RelIsPartition); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
 // tables (and related things like views)
 // https://www.postgresql.org/docs/9.6/static/catalog-pg-class.html
 type schemaClass struct {
-	RelName string
-	RelType pgx.Oid
-	RelAm   pgx.Oid
-	RelKind string
+	RelName             string
+	RelType             objectID
+	RelAm               objectID
+	RelKind             string
+	RelIsPopulated      bool
+	RelIsPartition      bool
+	RelRowSecurity      bool
+	RelForceRowSecurity bool
+	RelOwner            string
+	RelACL              []string
+	RelOptions          []string
+	ViewDef             string // pg_get_viewdef(), for views and materialized views
+	PartBound           string // relpartbound, for partitions
 }
 
-func pgClass(conn queryer, namespace pgx.Oid) (map[pgx.Oid]schemaClass, error) {
-	rows, err := conn.Query(`
+func pgClass(ctx context.Context, conn Queryer, namespace objectID) (map[objectID]schemaClass, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
-				oid, relname, reltype, relam, relkind
+				oid, relname, reltype, relam, relkind::text, relispopulated, relispartition,
+				relrowsecurity, relforcerowsecurity, to_json(reloptions)::text,
+				pg_catalog.pg_get_userbyid(relowner)::text,
+				to_json(COALESCE(relacl, acldefault(CASE WHEN relkind = 'S' THEN 's' ELSE 'r' END::"char", relowner))::text[])::text,
+				CASE WHEN relkind IN ('v', 'm') THEN pg_catalog.pg_get_viewdef(oid) ELSE '' END,
+				COALESCE(pg_catalog.pg_get_expr(relpartbound, oid), '')
 			FROM
 				pg_catalog.pg_class
 			WHERE
-				relnamespace=$1
+				relnamespace = $1::int8::oid
 		`, namespace)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaClass{}
+	var res = map[objectID]schemaClass{}
 	for rows.Next() {
 		var (
 			t   schemaClass
-			oid pgx.Oid
+			oid objectID
 		)
-		if err := rows.Scan(&oid, &t.RelName, &t.RelType, &t.RelAm, &t.RelKind); err != nil {
+		if err := rows.Scan(
+			&oid,
+			&t.RelName,
+			&t.RelType,
+			&t.RelAm,
+			&t.RelKind,
+			&t.RelIsPopulated,
+			&t.RelIsPartition,
+			&t.RelRowSecurity,
+			&t.RelForceRowSecurity,
+			asJSON(&t.RelOptions),
+			&t.RelOwner,
+			asJSON(&t.RelACL),
+			&t.ViewDef,
+			&t.PartBound,
+		); err != nil {
 			return nil, err
 		}
 		res[oid] = t
@@ -80,17 +156,25 @@ func pgClass(conn queryer, namespace pgx.Oid) (map[pgx.Oid]schemaClass, error) {
 // columns
 // https://www.postgresql.org/docs/9.6/static/catalog-pg-attribute.html
 type schemaAttribute struct {
-	AttRelID   pgx.Oid
-	AttName    string
-	AttTypID   pgx.Oid
-	AttNum     int
-	AttNotNull bool
+	AttRelID     objectID
+	AttName      string
+	AttTypID     objectID
+	AttNum       int
+	AttNotNull   bool
+	AttIsDropped bool
+	AttIdentity  string
+	AttGenerated string
+	FormatType   string
+	AttACL       []string
 }
 
-func pgAttribute(conn queryer) ([]schemaAttribute, error) {
-	rows, err := conn.Query(`
+func pgAttribute(ctx context.Context, conn Queryer) ([]schemaAttribute, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
-				attrelid, attname, atttypid, attnum, attnotnull
+				attrelid, attname, atttypid, attnum, attnotnull, attisdropped,
+				attidentity::text, attgenerated::text,
+				COALESCE(pg_catalog.format_type(atttypid, atttypmod), ''),
+				to_json(attacl::text[])::text
 			FROM
 				pg_catalog.pg_attribute
 		`)
@@ -108,6 +192,46 @@ func pgAttribute(conn queryer) ([]schemaAttribute, error) {
 			&c.AttTypID,
 			&c.AttNum,
 			&c.AttNotNull,
+			&c.AttIsDropped,
+			&c.AttIdentity,
+			&c.AttGenerated,
+			&c.FormatType,
+			asJSON(&c.AttACL),
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// column defaults, and expressions of generated columns
+// https://www.postgresql.org/docs/12/catalog-pg-attrdef.html
+type schemaAttrDef struct {
+	AdRelID objectID
+	AdNum   int
+	AdSrc   string
+}
+
+func pgAttrDef(ctx context.Context, conn Queryer) ([]schemaAttrDef, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				adrelid, adnum, pg_catalog.pg_get_expr(adbin, adrelid)
+			FROM
+				pg_catalog.pg_attrdef
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaAttrDef
+	for rows.Next() {
+		var c schemaAttrDef
+		if err := rows.Scan(
+			&c.AdRelID,
+			&c.AdNum,
+			&c.AdSrc,
 		); err != nil {
 			return nil, err
 		}
@@ -117,16 +241,36 @@ func pgAttribute(conn queryer) ([]schemaAttribute, error) {
 }
 
 // types
-// https://www.postgresql.org/docs/9.6/static/catalog-pg-type.html
+// https://www.postgresql.org/docs/12/catalog-pg-type.html
 type schemaType struct {
-	TypName string
-	TypElem pgx.Oid
+	TypName      string
+	TypNamespace objectID
+	TypType      string
+	TypElem      objectID
+	TypCategory  string
+	TypRelID     objectID
+	TypNotNull   bool
+	TypDefault   string
+	BaseType     string // format_type() of the base type of a domain
+	TypOwner     string
+	TypACL       []string
 }
 
-func pgType(conn queryer) (map[pgx.Oid]schemaType, error) {
-	rows, err := conn.Query(`
+// isArray is true for array types. Some other types, such as point and name,
+// also have a typelem.
+func (t schemaType) isArray() bool {
+	return t.TypCategory == "A"
+}
+
+func pgType(ctx context.Context, conn Queryer) (map[objectID]schemaType, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
-				oid, typname, typelem
+				oid, typname, typnamespace, typtype::text, typelem,
+				typcategory::text, typrelid,
+				typnotnull, COALESCE(typdefault, ''),
+				CASE WHEN typtype = 'd' THEN pg_catalog.format_type(typbasetype, typtypmod) ELSE '' END,
+				pg_catalog.pg_get_userbyid(typowner)::text,
+				to_json(COALESCE(typacl, acldefault('T', typowner))::text[])::text
 			FROM
 				pg_catalog.pg_type
 		`)
@@ -135,16 +279,111 @@ func pgType(conn queryer) (map[pgx.Oid]schemaType, error) {
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaType{}
+	var res = map[objectID]schemaType{}
 	for rows.Next() {
 		var (
 			c   schemaType
-			oid pgx.Oid
+			oid objectID
 		)
 		if err := rows.Scan(
 			&oid,
 			&c.TypName,
+			&c.TypNamespace,
+			&c.TypType,
 			&c.TypElem,
+			&c.TypCategory,
+			&c.TypRelID,
+			&c.TypNotNull,
+			&c.TypDefault,
+			&c.BaseType,
+			&c.TypOwner,
+			asJSON(&c.TypACL),
+		); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
+// enum labels
+// https://www.postgresql.org/docs/12/catalog-pg-enum.html
+type schemaEnum struct {
+	EnumTypID objectID
+	EnumLabel string
+}
+
+// pgEnum gives the enum labels in their sort order
+func pgEnum(ctx context.Context, conn Queryer) ([]schemaEnum, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				enumtypid, enumlabel
+			FROM
+				pg_catalog.pg_enum
+			ORDER BY
+				enumtypid, enumsortorder
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaEnum
+	for rows.Next() {
+		var c schemaEnum
+		if err := rows.Scan(
+			&c.EnumTypID,
+			&c.EnumLabel,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// range types
+// https://www.postgresql.org/docs/12/catalog-pg-range.html
+type schemaRange struct {
+	RngSubtype   objectID
+	RngCollation objectID
+	RngCanonical string
+	RngSubDiff   string
+	RngMultiTyp  objectID
+}
+
+func pgRange(ctx context.Context, conn Queryer, version int) (map[objectID]schemaRange, error) {
+	multirange := "0::oid"
+	if version >= 140000 {
+		multirange = "rngmultitypid"
+	}
+	rows, err := conn.Query(ctx, `
+			SELECT
+				rngtypid, rngsubtype, rngcollation,
+				COALESCE(NULLIF(rngcanonical::oid, 0)::regproc::text, ''),
+				COALESCE(NULLIF(rngsubdiff::oid, 0)::regproc::text, ''),
+				`+multirange+`
+			FROM
+				pg_catalog.pg_range
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaRange{}
+	for rows.Next() {
+		var (
+			c   schemaRange
+			oid objectID
+		)
+		if err := rows.Scan(
+			&oid,
+			&c.RngSubtype,
+			&c.RngCollation,
+			&c.RngCanonical,
+			&c.RngSubDiff,
+			&c.RngMultiTyp,
 		); err != nil {
 			return nil, err
 		}
@@ -156,14 +395,19 @@ func pgType(conn queryer) (map[pgx.Oid]schemaType, error) {
 // inheritence
 // https://www.postgresql.org/docs/9.6/static/catalog-pg-inherits.html
 type schemaInherits struct {
-	InhRelID, InhParent pgx.Oid
+	InhRelID, InhParent objectID
 	InhSeqNo            int
+	InhDetachPending    bool
 }
 
-func pgInherits(conn queryer) ([]schemaInherits, error) {
-	rows, err := conn.Query(`
+func pgInherits(ctx context.Context, conn Queryer, version int) ([]schemaInherits, error) {
+	detachPending := "false"
+	if version >= 140000 {
+		detachPending = "inhdetachpending"
+	}
+	rows, err := conn.Query(ctx, `
 			SELECT
-				inhrelid, inhparent, inhseqno
+				inhrelid, inhparent, inhseqno, `+detachPending+`
 			FROM
 				pg_catalog.pg_inherits
 		`)
@@ -179,6 +423,7 @@ func pgInherits(conn queryer) ([]schemaInherits, error) {
 			&c.InhRelID,
 			&c.InhParent,
 			&c.InhSeqNo,
+			&c.InhDetachPending,
 		); err != nil {
 			return nil, err
 		}
@@ -187,25 +432,92 @@ func pgInherits(conn queryer) ([]schemaInherits, error) {
 	return res, rows.Err()
 }
 
+// partitioned tables
+// https://www.postgresql.org/docs/12/catalog-pg-partitioned-table.html
+type schemaPartitionedTable struct {
+	PartStrat  string
+	PartDefID  objectID
+	PartKeyDef string // pg_get_partkeydef()
+}
+
+func pgPartitionedTable(ctx context.Context, conn Queryer) (map[objectID]schemaPartitionedTable, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				partrelid, partstrat::text, partdefid,
+				pg_catalog.pg_get_partkeydef(partrelid)
+			FROM
+				pg_catalog.pg_partitioned_table
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaPartitionedTable{}
+	for rows.Next() {
+		var (
+			c   schemaPartitionedTable
+			oid objectID
+		)
+		if err := rows.Scan(
+			&oid,
+			&c.PartStrat,
+			&c.PartDefID,
+			&c.PartKeyDef,
+		); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
 // index
 // This is in addition to the entries in pg_class
-// https://www.postgresql.org/docs/9.6/static/catalog-pg-index.html
+// https://www.postgresql.org/docs/12/catalog-pg-index.html
 type schemaIndex struct {
-	IndexRelID   pgx.Oid
-	IndRelID     pgx.Oid
-	IndIsUnique  bool
-	IndIsPrimary bool
-	IndKey       []int32
+	IndexRelID          objectID
+	IndRelID            objectID
+	IndNKeyAtts         int
+	IndIsUnique         bool
+	IndNullsNotDistinct bool
+	IndIsPrimary        bool
+	IndImmediate        bool
+	IndIsClustered      bool
+	IndIsValid          bool
+	IndIsReady          bool
+	IndIsLive           bool
+	IndIsReplIdent      bool
+	IndKey              []int32
+	IndCollation        []objectID
+	IndClass            []objectID
+	IndOption           []int32
+	IndPred             string   // partial index WHERE expression
+	KeyDefs             []string // pg_get_indexdef() of every column, also for expressions
+	IndexDef            string   // pg_get_indexdef()
 }
 
 // pgIndex mapped to the pg_class entry they belong to
-func pgIndex(conn queryer) (map[pgx.Oid]schemaIndex, error) {
-	// TODO: expressions can be rendered with:
-	// > pg_get_expr(indexprs, indrelid) as expression
-	// But no idea how to use that with multiple expressions.
-	rows, err := conn.Query(`
+func pgIndex(ctx context.Context, conn Queryer, version int) (map[objectID]schemaIndex, error) {
+	nullsNotDistinct := "false"
+	if version >= 150000 {
+		nullsNotDistinct = "indnullsnotdistinct"
+	}
+	rows, err := conn.Query(ctx, `
 			SELECT
-				indexrelid, indrelid, indisunique, indisprimary, indkey[0:array_length(indkey, 1)]::int4[]
+				indexrelid, indrelid, indnkeyatts, indisunique, `+nullsNotDistinct+`, indisprimary,
+				indimmediate, indisclustered, indisvalid, indisready, indislive, indisreplident,
+				to_json(indkey[0:array_length(indkey, 1)]::int4[])::text,
+				to_json(indcollation[0:array_length(indcollation, 1)]::int8[])::text,
+				to_json(indclass[0:array_length(indclass, 1)]::int8[])::text,
+				to_json(indoption[0:array_length(indoption, 1)]::int4[])::text,
+				COALESCE(pg_catalog.pg_get_expr(indpred, indrelid), ''),
+				to_json(ARRAY(
+					SELECT pg_catalog.pg_get_indexdef(indexrelid, k, false)
+					FROM generate_series(1, indnatts) k
+					ORDER BY k
+				))::text,
+				pg_catalog.pg_get_indexdef(indexrelid)
 			FROM
 				pg_catalog.pg_index
 		`)
@@ -214,15 +526,29 @@ func pgIndex(conn queryer) (map[pgx.Oid]schemaIndex, error) {
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaIndex{}
+	var res = map[objectID]schemaIndex{}
 	for rows.Next() {
 		var c schemaIndex
 		if err := rows.Scan(
 			&c.IndexRelID,
 			&c.IndRelID,
+			&c.IndNKeyAtts,
 			&c.IndIsUnique,
+			&c.IndNullsNotDistinct,
 			&c.IndIsPrimary,
-			&c.IndKey,
+			&c.IndImmediate,
+			&c.IndIsClustered,
+			&c.IndIsValid,
+			&c.IndIsReady,
+			&c.IndIsLive,
+			&c.IndIsReplIdent,
+			asJSON(&c.IndKey),
+			asJSON(&c.IndCollation),
+			asJSON(&c.IndClass),
+			asJSON(&c.IndOption),
+			&c.IndPred,
+			asJSON(&c.KeyDefs),
+			&c.IndexDef,
 		); err != nil {
 			return nil, err
 		}
@@ -231,14 +557,86 @@ func pgIndex(conn queryer) (map[pgx.Oid]schemaIndex, error) {
 	return res, rows.Err()
 }
 
+// operator classes
+// https://www.postgresql.org/docs/12/catalog-pg-opclass.html
+type schemaOpClass struct {
+	OpcName    string
+	OpcDefault bool
+}
+
+func pgOpClass(ctx context.Context, conn Queryer) (map[objectID]schemaOpClass, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				oid, opcname, opcdefault
+			FROM
+				pg_catalog.pg_opclass
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaOpClass{}
+	for rows.Next() {
+		var (
+			c   schemaOpClass
+			oid objectID
+		)
+		if err := rows.Scan(
+			&oid,
+			&c.OpcName,
+			&c.OpcDefault,
+		); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
+// collations
+// https://www.postgresql.org/docs/12/catalog-pg-collation.html
+type schemaCollation struct {
+	CollName string
+}
+
+func pgCollation(ctx context.Context, conn Queryer) (map[objectID]schemaCollation, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				oid, collname
+			FROM
+				pg_catalog.pg_collation
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaCollation{}
+	for rows.Next() {
+		var (
+			c   schemaCollation
+			oid objectID
+		)
+		if err := rows.Scan(
+			&oid,
+			&c.CollName,
+		); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
 // am (access methods)
 // https://www.postgresql.org/docs/9.6/static/catalog-pg-am.html
 type schemaAm struct {
 	AmName string
 }
 
-func pgAm(conn queryer) (map[pgx.Oid]schemaAm, error) {
-	rows, err := conn.Query(`
+func pgAm(ctx context.Context, conn Queryer) (map[objectID]schemaAm, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
 				oid, amname
 			FROM
@@ -249,11 +647,11 @@ func pgAm(conn queryer) (map[pgx.Oid]schemaAm, error) {
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaAm{}
+	var res = map[objectID]schemaAm{}
 	for rows.Next() {
 		var (
 			c   schemaAm
-			oid pgx.Oid
+			oid objectID
 		)
 		if err := rows.Scan(
 			&oid,
@@ -266,60 +664,460 @@ func pgAm(conn queryer) (map[pgx.Oid]schemaAm, error) {
 	return res, rows.Err()
 }
 
-func loadSequence(tx *pgx.Tx, schema, seq string) (Sequence, error) {
-	row := tx.QueryRow(fmt.Sprintf(`
+// constraints
+// https://www.postgresql.org/docs/9.6/static/catalog-pg-constraint.html
+type schemaConstraint struct {
+	ConName       string
+	ConType       string
+	ConDeferrable bool
+	ConDeferred   bool
+	ConValidated  bool
+	ConRelID      objectID
+	ConTypID      objectID
+	ConIndID      objectID
+	ConFRelID     objectID
+	ConFUpdType   string
+	ConFDelType   string
+	ConKey        []int32
+	ConFKey       []int32
+	ConstraintDef string
+}
+
+func pgConstraint(ctx context.Context, conn Queryer, namespace objectID) (map[objectID]schemaConstraint, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				oid, conname, contype::text, condeferrable, condeferred, convalidated,
+				conrelid, contypid, conindid, confrelid,
+				confupdtype::text, confdeltype::text,
+				to_json(conkey::int4[])::text, to_json(confkey::int4[])::text,
+				pg_catalog.pg_get_constraintdef(oid)
+			FROM
+				pg_catalog.pg_constraint
+			WHERE
+				connamespace = $1::int8::oid
+		`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[objectID]schemaConstraint{}
+	for rows.Next() {
+		var (
+			c   schemaConstraint
+			oid objectID
+		)
+		if err := rows.Scan(
+			&oid,
+			&c.ConName,
+			&c.ConType,
+			&c.ConDeferrable,
+			&c.ConDeferred,
+			&c.ConValidated,
+			&c.ConRelID,
+			&c.ConTypID,
+			&c.ConIndID,
+			&c.ConFRelID,
+			&c.ConFUpdType,
+			&c.ConFDelType,
+			asJSON(&c.ConKey),
+			asJSON(&c.ConFKey),
+			&c.ConstraintDef,
+		); err != nil {
+			return nil, err
+		}
+		res[oid] = c
+	}
+	return res, rows.Err()
+}
+
+// triggers
+// https://www.postgresql.org/docs/12/catalog-pg-trigger.html
+type schemaTrigger struct {
+	OID          objectID
+	TgRelID      objectID
+	TgName       string
+	TgFunction   string // tgfoid as regproc
+	TgType       int
+	TgEnabled    string
+	TgConstraint bool
+	TgAttr       []int32
+	TgArgs       []byte
+	TgOldTable   string
+	TgNewTable   string
+	TriggerDef   string // pg_get_triggerdef()
+}
+
+// pgTrigger gives the non-internal triggers on tables in the namespace.
+func pgTrigger(ctx context.Context, conn Queryer, namespace objectID) ([]schemaTrigger, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				t.oid, t.tgrelid, t.tgname, t.tgfoid::regproc::text, t.tgtype::int4,
+				t.tgenabled::text, t.tgconstraint <> 0,
+				to_json(t.tgattr[0:array_length(t.tgattr, 1)]::int4[])::text, t.tgargs,
+				COALESCE(t.tgoldtable::text, ''), COALESCE(t.tgnewtable::text, ''),
+				pg_catalog.pg_get_triggerdef(t.oid)
+			FROM
+				pg_catalog.pg_trigger t
+				JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid
+			WHERE
+				c.relnamespace = $1::int8::oid
+				AND NOT t.tgisinternal
+		`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaTrigger
+	for rows.Next() {
+		var c schemaTrigger
+		if err := rows.Scan(
+			&c.OID,
+			&c.TgRelID,
+			&c.TgName,
+			&c.TgFunction,
+			&c.TgType,
+			&c.TgEnabled,
+			&c.TgConstraint,
+			asJSON(&c.TgAttr),
+			&c.TgArgs,
+			&c.TgOldTable,
+			&c.TgNewTable,
+			&c.TriggerDef,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// row level security policies
+// https://www.postgresql.org/docs/12/catalog-pg-policy.html
+type schemaPolicy struct {
+	OID           objectID
+	PolName       string
+	PolRelID      objectID
+	PolCmd        string
+	PolPermissive bool
+	PolRoles      []string // role names, "public" for PUBLIC
+	PolQual       string
+	PolWithCheck  string
+}
+
+// pgPolicy gives the policies on tables in the namespace.
+func pgPolicy(ctx context.Context, conn Queryer, namespace objectID) ([]schemaPolicy, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				p.oid, p.polname, p.polrelid, p.polcmd::text, p.polpermissive,
+				to_json(ARRAY(
+					SELECT CASE WHEN r = 0 THEN 'public' ELSE pg_catalog.pg_get_userbyid(r)::text END
+					FROM unnest(p.polroles) r
+				))::text,
+				COALESCE(pg_catalog.pg_get_expr(p.polqual, p.polrelid), ''),
+				COALESCE(pg_catalog.pg_get_expr(p.polwithcheck, p.polrelid), '')
+			FROM
+				pg_catalog.pg_policy p
+				JOIN pg_catalog.pg_class c ON c.oid = p.polrelid
+			WHERE
+				c.relnamespace = $1::int8::oid
+		`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaPolicy
+	for rows.Next() {
+		var c schemaPolicy
+		if err := rows.Scan(
+			&c.OID,
+			&c.PolName,
+			&c.PolRelID,
+			&c.PolCmd,
+			&c.PolPermissive,
+			asJSON(&c.PolRoles),
+			&c.PolQual,
+			&c.PolWithCheck,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// event triggers
+// https://www.postgresql.org/docs/12/catalog-pg-event-trigger.html
+type schemaEventTrigger struct {
+	OID         objectID
+	EvtName     string
+	EvtEvent    string
+	EvtFunction string // evtfoid as regproc
+	EvtEnabled  string
+	EvtTags     []string
+}
+
+func pgEventTrigger(ctx context.Context, conn Queryer) ([]schemaEventTrigger, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				oid, evtname, evtevent, evtfoid::regproc::text, evtenabled::text, to_json(evttags)::text
+			FROM
+				pg_catalog.pg_event_trigger
+		`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaEventTrigger
+	for rows.Next() {
+		var c schemaEventTrigger
+		if err := rows.Scan(
+			&c.OID,
+			&c.EvtName,
+			&c.EvtEvent,
+			&c.EvtFunction,
+			&c.EvtEnabled,
+			asJSON(&c.EvtTags),
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// comments
+// https://www.postgresql.org/docs/12/catalog-pg-description.html
+type schemaDescription struct {
+	ObjOID      objectID
+	ClassOID    string // the catalog, such as "pg_class"
+	ObjSubID    int
+	Description string
+}
+
+// pgDescription gives the comments on the namespace, on the objects in it,
+// and on the event triggers.
+func pgDescription(ctx context.Context, conn Queryer, namespace objectID) ([]schemaDescription, error) {
+	rows, err := conn.Query(ctx, `
+			SELECT
+				d.objoid, d.classoid::regclass::text, d.objsubid, d.description
+			FROM
+				pg_catalog.pg_description d
+			WHERE
+				CASE d.classoid
+				WHEN 'pg_catalog.pg_namespace'::regclass THEN
+					d.objoid = $1::int8::oid
+				WHEN 'pg_catalog.pg_class'::regclass THEN
+					d.objoid IN (SELECT oid FROM pg_catalog.pg_class WHERE relnamespace = $1::int8::oid)
+				WHEN 'pg_catalog.pg_type'::regclass THEN
+					d.objoid IN (SELECT oid FROM pg_catalog.pg_type WHERE typnamespace = $1::int8::oid)
+				WHEN 'pg_catalog.pg_proc'::regclass THEN
+					d.objoid IN (SELECT oid FROM pg_catalog.pg_proc WHERE pronamespace = $1::int8::oid)
+				WHEN 'pg_catalog.pg_constraint'::regclass THEN
+					d.objoid IN (SELECT oid FROM pg_catalog.pg_constraint WHERE connamespace = $1::int8::oid)
+				WHEN 'pg_catalog.pg_trigger'::regclass THEN
+					d.objoid IN (
+						SELECT t.oid
+						FROM pg_catalog.pg_trigger t JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid
+						WHERE c.relnamespace = $1::int8::oid
+					)
+				WHEN 'pg_catalog.pg_policy'::regclass THEN
+					d.objoid IN (
+						SELECT p.oid
+						FROM pg_catalog.pg_policy p JOIN pg_catalog.pg_class c ON c.oid = p.polrelid
+						WHERE c.relnamespace = $1::int8::oid
+					)
+				WHEN 'pg_catalog.pg_event_trigger'::regclass THEN
+					true
+				ELSE
+					false
+				END
+		`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []schemaDescription
+	for rows.Next() {
+		var c schemaDescription
+		if err := rows.Scan(
+			&c.ObjOID,
+			&c.ClassOID,
+			&c.ObjSubID,
+			&c.Description,
+		); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// sequences, with the column they're OWNED BY, if any
+// https://www.postgresql.org/docs/12/catalog-pg-sequence.html
+type schemaSequence struct {
+	SeqRelID     objectID
+	SeqType      string // format_type(seqtypid)
+	SeqStart     int
+	SeqIncrement int
+	SeqMax       int
+	SeqMin       int
+	SeqCache     int
+	SeqCycle     bool
+	LastValue    int
+	IsCalled     bool // false if nextval() was never called, or not readable
+	OwnedBy      objectID
+	OwnedByCol   int
+}
+
+func pgSequence(ctx context.Context, conn Queryer, namespace objectID) (map[objectID]schemaSequence, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
-				start_value, increment_by, max_value, min_value, is_cycled
+				s.seqrelid, pg_catalog.format_type(s.seqtypid, NULL),
+				s.seqstart, s.seqincrement, s.seqmax, s.seqmin, s.seqcache,
+				s.seqcycle,
+				COALESCE(l.last_value, 0), l.last_value IS NOT NULL,
+				COALESCE(d.refobjid, 0), COALESCE(d.refobjsubid, 0)
 			FROM
-				%s.%s
-		`, schema, seq))
-	var s Sequence
-	err := row.Scan(
-		&s.Start,
-		&s.IncrementBy,
-		&s.MaxValue,
-		&s.MinValue,
-		&s.Cycle,
-	)
-	return s, err
+				pg_catalog.pg_sequence s
+				JOIN pg_catalog.pg_class c ON c.oid = s.seqrelid
+				CROSS JOIN LATERAL (
+					SELECT CASE WHEN pg_catalog.has_sequence_privilege(s.seqrelid, 'SELECT,USAGE')
+						THEN pg_catalog.pg_sequence_last_value(s.seqrelid)
+					END AS last_value
+				) l
+				LEFT JOIN pg_catalog.pg_depend d
+					ON d.classid = 'pg_catalog.pg_class'::regclass
+					AND d.objid = s.seqrelid
+					AND d.refclassid = 'pg_catalog.pg_class'::regclass
+					AND d.deptype IN ('a', 'i')
+			WHERE
+				c.relnamespace = $1::int8::oid
+		`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[objectID]schemaSequence{}
+	for rows.Next() {
+		var c schemaSequence
+		if err := rows.Scan(
+			&c.SeqRelID,
+			&c.SeqType,
+			&c.SeqStart,
+			&c.SeqIncrement,
+			&c.SeqMax,
+			&c.SeqMin,
+			&c.SeqCache,
+			&c.SeqCycle,
+			&c.LastValue,
+			&c.IsCalled,
+			&c.OwnedBy,
+			&c.OwnedByCol,
+		); err != nil {
+			return nil, err
+		}
+		res[c.SeqRelID] = c
+	}
+	return res, rows.Err()
 }
 
 // functions
-// https://www.postgresql.org/docs/9.6/static/catalog-pg-proc.html
+// https://www.postgresql.org/docs/12/catalog-pg-proc.html
 type schemaProc struct {
-	ProName     string
-	ProLang     pgx.Oid
-	ProArgTypes []pgx.Oid
-	ProSrc      string
+	ProName         string
+	ProKind         string
+	ProLang         objectID
+	ProCost         float64
+	ProRows         float64
+	ProSecDef       bool
+	ProLeakproof    bool
+	ProIsStrict     bool
+	ProRetSet       bool
+	ProVolatile     string
+	ProParallel     string
+	ProRetType      objectID
+	ProArgTypes     []objectID
+	ProAllArgTypes  []objectID // nil if all arguments are IN arguments
+	ProArgModes     []string   // nil if all arguments are IN arguments
+	ProArgNames     []string
+	ProNArgDefaults int
+	ProArgDefaults  string // pg_get_expr(proargdefaults), comma separated
+	ProSrc          string
+	ProConfig       []string
+	ProOwner        string
+	ProACL          []string
+	FunctionResult  string // pg_get_function_result()
+	FunctionDef     string // pg_get_functiondef()
 }
 
-func pgProc(conn queryer, namespace pgx.Oid) (map[pgx.Oid]schemaProc, error) {
-	rows, err := conn.Query(`
+func pgProc(ctx context.Context, conn Queryer, namespace objectID) (map[objectID]schemaProc, error) {
+	// pg_get_functiondef() doesn't work on aggregates
+	rows, err := conn.Query(ctx, `
 			SELECT
-				oid, proname, prolang, proargtypes[0:array_length(proargtypes, 1)]::int4[], prosrc
+				oid, proname, prokind::text, prolang,
+				procost::float8, prorows::float8,
+				prosecdef, proleakproof, proisstrict, proretset,
+				provolatile::text, proparallel::text, prorettype,
+				to_json(proargtypes[0:array_length(proargtypes, 1)]::int8[])::text,
+				to_json(proallargtypes::int8[])::text, to_json(proargmodes::text[])::text,
+				to_json(proargnames)::text, pronargdefaults,
+				COALESCE(pg_catalog.pg_get_expr(proargdefaults, 0), ''),
+				prosrc, to_json(proconfig)::text,
+				pg_catalog.pg_get_userbyid(proowner)::text,
+				to_json(COALESCE(proacl, acldefault('f', proowner))::text[])::text,
+				COALESCE(pg_catalog.pg_get_function_result(oid), ''),
+				CASE WHEN prokind IN ('f', 'p') THEN pg_catalog.pg_get_functiondef(oid) ELSE '' END
 			FROM
 				pg_catalog.pg_proc
 			WHERE
-				pronamespace=$1
+				pronamespace = $1::int8::oid
 		`, namespace)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaProc{}
+	var res = map[objectID]schemaProc{}
 	for rows.Next() {
 		var (
 			t   schemaProc
-			oid pgx.Oid
-			pat []int32
+			oid objectID
 		)
-		if err := rows.Scan(&oid, &t.ProName, &t.ProLang, &pat, &t.ProSrc); err != nil {
+		if err := rows.Scan(
+			&oid,
+			&t.ProName,
+			&t.ProKind,
+			&t.ProLang,
+			&t.ProCost,
+			&t.ProRows,
+			&t.ProSecDef,
+			&t.ProLeakproof,
+			&t.ProIsStrict,
+			&t.ProRetSet,
+			&t.ProVolatile,
+			&t.ProParallel,
+			&t.ProRetType,
+			asJSON(&t.ProArgTypes),
+			asJSON(&t.ProAllArgTypes),
+			asJSON(&t.ProArgModes),
+			asJSON(&t.ProArgNames),
+			&t.ProNArgDefaults,
+			&t.ProArgDefaults,
+			&t.ProSrc,
+			asJSON(&t.ProConfig),
+			&t.ProOwner,
+			asJSON(&t.ProACL),
+			&t.FunctionResult,
+			&t.FunctionDef,
+		); err != nil {
 			return nil, err
 		}
-		for _, o := range pat {
-			t.ProArgTypes = append(t.ProArgTypes, pgx.Oid(o))
-		}
 		res[oid] = t
 	}
 	return res, rows.Err()
@@ -331,8 +1129,8 @@ type schemaLanguage struct {
 	LanName string
 }
 
-func pgLanguage(conn queryer) (map[pgx.Oid]schemaLanguage, error) {
-	rows, err := conn.Query(`
+func pgLanguage(ctx context.Context, conn Queryer) (map[objectID]schemaLanguage, error) {
+	rows, err := conn.Query(ctx, `
 			SELECT
 				oid, lanname
 			FROM
@@ -343,11 +1141,11 @@ func pgLanguage(conn queryer) (map[pgx.Oid]schemaLanguage, error) {
 	}
 	defer rows.Close()
 
-	var res = map[pgx.Oid]schemaLanguage{}
+	var res = map[objectID]schemaLanguage{}
 	for rows.Next() {
 		var (
 			t   schemaLanguage
-			oid pgx.Oid
+			oid objectID
 		)
 		if err := rows.Scan(&oid, &t.LanName); err != nil {
 			return nil, err
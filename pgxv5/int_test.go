@@ -0,0 +1,92 @@
+// +build int
+
+package pgxv5
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/schemaspy"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	intPGURL = "postgres://@localhost/schemaspy"
+)
+
+func TestDescribe(t *testing.T) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, intPGURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	d, err := Describe(ctx, db, "schemaspyint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(d.Tables), 16; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	tab := d.Relations["simple"]
+	if have, want := tab.ColumnNames(), []string{"id", "name", "t"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	all, err := DescribeDatabase(ctx, db, "schemaspyint", "schemaspyint_other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := all.Schemas["schemaspyint"], d; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+// sleeper runs a slow query before every query.
+type sleeper struct {
+	q schemaspy.Queryer
+}
+
+func (s sleeper) Query(ctx context.Context, sql string, args ...interface{}) (schemaspy.Rows, error) {
+	rows, err := s.q.Query(ctx, "SELECT pg_sleep(10)")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.q.Query(ctx, sql, args...)
+}
+
+func TestCancelQuery(t *testing.T) {
+	db, err := pgxpool.New(context.Background(), intPGURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(context.Background())
+
+	// no statement_timeout, pgx cancels the running query
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = schemaspy.Describe(ctx, sleeper{Queryer(tx)}, "schemaspyint")
+	var te *schemaspy.TimeoutError
+	if have, want := errors.As(err, &te), true; have != want {
+		t.Fatalf("have %#v, want %#v", have, want)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("query was not cancelled, took %s", d)
+	}
+}
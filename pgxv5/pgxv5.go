@@ -0,0 +1,99 @@
+// Package pgxv5 has the schemaspy adapters for github.com/jackc/pgx/v5.
+package pgxv5
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alicebob/schemaspy"
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier is implemented by *pgxpool.Pool, *pgx.Conn, and pgx.Tx.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Beginner is implemented by *pgxpool.Pool and *pgx.Conn.
+type Beginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Queryer makes a schemaspy.Queryer. Use a pgx.Tx to get a consistent
+// result, or see Describe().
+func Queryer(q Querier) schemaspy.Queryer {
+	return queryer{q}
+}
+
+type queryer struct {
+	q Querier
+}
+
+func (q queryer) Query(ctx context.Context, sql string, args ...interface{}) (schemaspy.Rows, error) {
+	r, err := q.q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows{r}, nil
+}
+
+type rows struct {
+	pgx.Rows
+}
+
+func (r rows) Close() error {
+	r.Rows.Close()
+	return nil
+}
+
+// Describe a schema in a read only, repeatable read, transaction. Leave
+// schema empty for the public schema. If the context has a deadline it's
+// also used for statement_timeout and lock_timeout.
+func Describe(ctx context.Context, db Beginner, schema string) (*schemaspy.Schema, error) {
+	tx, err := begin(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return schemaspy.Describe(ctx, Queryer(tx), schema)
+}
+
+// DescribeDatabase describes multiple schemas, in a single transaction. See
+// schemaspy.DescribeDatabase() and Describe().
+func DescribeDatabase(ctx context.Context, db Beginner, schemas ...string) (*schemaspy.Database, error) {
+	tx, err := begin(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return schemaspy.DescribeDatabase(ctx, Queryer(tx), schemas...)
+}
+
+// Public is a wrapper around Describe. It needs a pg URL (such as
+// "postgres://localhost"), and it'll return the public schema.
+func Public(ctx context.Context, pgURL string) (*schemaspy.Schema, error) {
+	conn, err := pgx.Connect(ctx, pgURL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+	return Describe(ctx, conn, "public")
+}
+
+func begin(ctx context.Context, db Beginner) (pgx.Tx, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		if cerr := ctx.Err(); errors.Is(cerr, context.DeadlineExceeded) {
+			return nil, &schemaspy.TimeoutError{Err: cerr}
+		}
+		return nil, err
+	}
+	if err := schemaspy.SetDeadline(ctx, Queryer(tx)); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
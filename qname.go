@@ -0,0 +1,66 @@
+package schemaspy
+
+import (
+	"sort"
+	"strings"
+)
+
+// QName is a schema qualified name, used when an object refers to another
+// object which can live in a different schema.
+type QName struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+}
+
+// String gives the name as it would be used in SQL, such as
+// `public.users` or `"Mixed".users`. The empty QName gives "".
+func (q QName) String() string {
+	if q.Name == "" {
+		return ""
+	}
+	if q.Schema == "" {
+		return quoteIdent(q.Name)
+	}
+	return quoteIdent(q.Schema) + "." + quoteIdent(q.Name)
+}
+
+// sortQNames orders by schema, and then by name.
+func sortQNames(qs []QName) {
+	sort.Slice(qs, func(i, j int) bool {
+		if qs[i].Schema != qs[j].Schema {
+			return qs[i].Schema < qs[j].Schema
+		}
+		return qs[i].Name < qs[j].Name
+	})
+}
+
+// quoteIdent quotes an identifier, but only if needed.
+func quoteIdent(s string) string {
+	if s != "" && !reservedKeywords[s] && !strings.ContainsAny(s[:1], "0123456789$") && strings.Trim(s, "abcdefghijklmnopqrstuvwxyz0123456789_$") == "" {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// https://www.postgresql.org/docs/current/sql-keywords-appendix.html
+// The keywords which are "reserved" or "reserved (can be function or type)".
+var reservedKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		all analyse analyze and any array as asc asymmetric authorization
+		binary both case cast check collate collation column concurrently
+		constraint create cross current_catalog current_date current_role
+		current_schema current_time current_timestamp current_user default
+		deferrable desc distinct do else end except false fetch for foreign
+		freeze from full grant group having ilike in initially inner intersect
+		into is isnull join lateral leading left like limit localtime
+		localtimestamp natural not notnull null offset on only or order outer
+		overlaps placing primary references returning right select
+		session_user similar some symmetric system_user table tablesample then
+		to trailing true union unique user using variadic verbose when where
+		window with
+	`) {
+		reservedKeywords[k] = true
+	}
+}
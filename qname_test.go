@@ -0,0 +1,23 @@
+package schemaspy
+
+import (
+	"testing"
+)
+
+func TestQNameString(t *testing.T) {
+	for q, want := range map[QName]string{
+		{}:                          "",
+		{"", "users"}:               "users",
+		{"public", "users"}:         "public.users",
+		{"Mixed", "users"}:          `"Mixed".users`,
+		{"public", "order"}:         `public."order"`,
+		{"public", "with space"}:    `public."with space"`,
+		{"public", `quo"te`}:        `public."quo""te"`,
+		{"public", "1st"}:           `public."1st"`,
+		{"public", "snake_case_$1"}: "public.snake_case_$1",
+	} {
+		if have := q.String(); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package schemaspy
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Queryer runs the catalog queries. All queries should see the same
+// snapshot, so this is usually a transaction. Use SQL() for database/sql, or
+// see the pgxv5 package.
+//
+// The queries only need a driver which can scan into basic Go types
+// (string, bool, int, float64, []byte) and into sql.Scanner values. Every
+// query gets the context, and the driver should cancel a running query when
+// the context is done, which database/sql and pgx do.
+type Queryer interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (Rows, error)
+}
+
+// Rows is the result of a Queryer.Query().
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// SQLQueryer is implemented by *sql.DB, *sql.Conn, and *sql.Tx.
+type SQLQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// SQL makes a Queryer from a database/sql value. Use a *sql.Tx to get a
+// consistent result, or see DescribeContext().
+func SQL(q SQLQueryer) Queryer {
+	return sqlQueryer{q}
+}
+
+type sqlQueryer struct {
+	q SQLQueryer
+}
+
+func (q sqlQueryer) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return q.q.QueryContext(ctx, sql, args...)
+}
+
+// DescribeContext describes a schema in a read only, repeatable read,
+// transaction. Leave schema empty for the public schema. If the context has
+// a deadline it's also used for statement_timeout and lock_timeout, see
+// SetDeadline(). Returns a *TimeoutError when the deadline of the context is
+// exceeded, or when a timeout is hit.
+func DescribeContext(ctx context.Context, db *sql.DB, schema string) (*Schema, error) {
+	return describeSQL(ctx, db, schema)
+}
+
+// DescribeConnContext is DescribeContext() on a single connection.
+func DescribeConnContext(ctx context.Context, conn *sql.Conn, schema string) (*Schema, error) {
+	return describeSQL(ctx, conn, schema)
+}
+
+// DescribeTxContext describes a schema in an existing transaction, which
+// should be "repeatable read" or "serializable" to get a consistent result.
+// Use SetTimeouts() on the transaction to limit how long the catalog
+// queries can take. This is Describe(ctx, SQL(tx), schema).
+func DescribeTxContext(ctx context.Context, tx *sql.Tx, schema string) (*Schema, error) {
+	return Describe(ctx, SQL(tx), schema)
+}
+
+// DescribeDatabaseContext is DescribeContext() for DescribeDatabase().
+func DescribeDatabaseContext(ctx context.Context, db *sql.DB, schemas ...string) (*Database, error) {
+	return describeDatabaseSQL(ctx, db, schemas)
+}
+
+// DescribeDatabaseConnContext is DescribeConnContext() for
+// DescribeDatabase().
+func DescribeDatabaseConnContext(ctx context.Context, conn *sql.Conn, schemas ...string) (*Database, error) {
+	return describeDatabaseSQL(ctx, conn, schemas)
+}
+
+// DescribeDatabaseTxContext is DescribeTxContext() for DescribeDatabase().
+func DescribeDatabaseTxContext(ctx context.Context, tx *sql.Tx, schemas ...string) (*Database, error) {
+	return DescribeDatabase(ctx, SQL(tx), schemas...)
+}
+
+// sqlBeginner is implemented by *sql.DB and *sql.Conn.
+type sqlBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+func describeSQL(ctx context.Context, db sqlBeginner, schema string) (*Schema, error) {
+	tx, err := beginSQL(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return Describe(ctx, SQL(tx), schema)
+}
+
+func describeDatabaseSQL(ctx context.Context, db sqlBeginner, schemas []string) (*Database, error) {
+	tx, err := beginSQL(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return DescribeDatabase(ctx, SQL(tx), schemas...)
+}
+
+// beginSQL starts a read only, repeatable read, transaction, with the
+// deadline of the context.
+func beginSQL(ctx context.Context, db sqlBeginner) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, asTimeout(ctx, err)
+	}
+	if err := SetDeadline(ctx, SQL(tx)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// objectID is a PostgreSQL oid. It scans from, and is passed as, an int8, so
+// it works with every driver. Queries need to use `$1::int8::oid`.
+type objectID uint32
+
+func (o *objectID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*o = objectID(v)
+	case uint32:
+		*o = objectID(v)
+	case []byte:
+		return o.parse(string(v))
+	case string:
+		return o.parse(v)
+	default:
+		return fmt.Errorf("can't scan %T into an oid", src)
+	}
+	return nil
+}
+
+func (o *objectID) parse(s string) error {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return err
+	}
+	*o = objectID(n)
+	return nil
+}
+
+func (o objectID) Value() (driver.Value, error) {
+	return int64(o), nil
+}
+
+// asJSON scans a to_json()::text column into v. This is how we read arrays,
+// since not every driver can scan those. NULL leaves v untouched.
+func asJSON(v interface{}) sql.Scanner {
+	return jsonScanner{v}
+}
+
+type jsonScanner struct {
+	v interface{}
+}
+
+func (j jsonScanner) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(s), j.v)
+	case []byte:
+		return json.Unmarshal(s, j.v)
+	default:
+		return fmt.Errorf("can't scan %T as JSON", src)
+	}
+}
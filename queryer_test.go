@@ -0,0 +1,66 @@
+package schemaspy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestObjectIDScan(t *testing.T) {
+	for _, c := range []struct {
+		src  interface{}
+		want objectID
+		err  string
+	}{
+		{int64(16384), 16384, ""},
+		{uint32(16384), 16384, ""},
+		{[]byte("16384"), 16384, ""},
+		{"4294967295", 4294967295, ""},
+		{"4294967296", 0, `strconv.ParseUint: parsing "4294967296": value out of range`},
+		{"oid", 0, `strconv.ParseUint: parsing "oid": invalid syntax`},
+		{nil, 0, "can't scan <nil> into an oid"},
+	} {
+		var o objectID
+		err := o.Scan(c.src)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%#v: have %v, want %s", c.src, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := o, c.want; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
+
+func TestAsJSON(t *testing.T) {
+	for _, c := range []struct {
+		src  interface{}
+		want []string
+		err  string
+	}{
+		{`["a","b"]`, []string{"a", "b"}, ""},
+		{[]byte(`["a"]`), []string{"a"}, ""},
+		{nil, []string{"untouched"}, ""},
+		{`[`, nil, "unexpected end of JSON input"},
+		{int64(1), nil, "can't scan int64 as JSON"},
+	} {
+		v := []string{"untouched"}
+		err := asJSON(&v).Scan(c.src)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%#v: have %v, want %s", c.src, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := v, c.want; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}
@@ -9,117 +9,445 @@
 package schemaspy
 
 import (
+	"context"
 	"fmt"
 	"sort"
-
-	"github.com/jackc/pgx"
+	"strings"
 )
 
 type Schema struct {
-	Name string
+	Name string `json:"name"`
+
+	Owner string `json:"owner"`
+	// Privileges on the schema itself
+	Privileges []Privilege `json:"privileges"`
 
-	// Relations are all tables, views, and materialized views
-	Relations map[string]Relation
+	// Relations are all tables, views, materialized views, and partitioned
+	// tables
+	Relations map[string]Relation `json:"relations"`
 
 	// all plain tables, ordered alphabetically. Every table has an entry in
 	// Relations
-	Tables []string
+	Tables []string `json:"tables"`
 
 	// all views, ordered alphabetically. Every view has an entry in Relations
-	Views []string
+	Views []string `json:"views"`
 
 	// all materialized views, ordered alphabetically. Every materialized view
 	// has an entry in Relations
-	Materialized []string
+	Materialized []string `json:"materialized"`
+
+	// all partitioned tables, ordered alphabetically. Every partitioned table
+	// has an entry in Relations. Partitions themselves are listed in Tables,
+	// or here if they are partitioned as well.
+	Partitioned []string `json:"partitioned"`
+
+	Sequences map[string]Sequence `json:"sequences"`
+
+	Indexes map[string]Index `json:"indexes"`
 
-	Sequences map[string]Sequence
+	// Constraints are all table constraints, by table name and then by
+	// constraint name. Constraint names are only unique per table. Every
+	// constraint is also listed in its Relation.
+	Constraints map[string]map[string]Constraint `json:"constraints"`
 
-	Indexes map[string]Index
+	// Types are the enums, composite types, domains, and range types,
+	// by name
+	Types map[string]Type `json:"types"`
 
-	Functions map[string]Function
+	// EventTriggers are the database wide event triggers, by name. They
+	// don't belong to a schema, but are included for completeness.
+	EventTriggers map[string]EventTrigger `json:"event_triggers"`
+
+	// Functions are keyed by their signature, such as "add(int4,int4)", so
+	// overloaded functions each have their own entry. See FunctionsByName().
+	Functions map[string]Function `json:"functions"`
+	// Comment is set with COMMENT ON SCHEMA. All the other objects have a
+	// Comment as well, set with COMMENT ON for that kind of object. It's
+	// empty if there is no comment.
+	Comment string `json:"comment"`
 }
 
-// Relation is a table, view, or materialized view.
+// Relation is a table, view, materialized view, or partitioned table.
 type Relation struct {
-	// Type is "table", "view", or "materialized view"
-	Type     string
-	Columns  map[string]Column
-	Inherits []string
-	Children []string
-	Indexes  []string
+	// Type is "table", "view", "materialized view", or "partitioned table"
+	Type    string            `json:"type"`
+	Columns map[string]Column `json:"columns"`
+	// Inherits are the parents, in INHERITS order
+	Inherits []QName `json:"inherits"`
+	// Children are the tables inheriting from this table, ordered by schema
+	// and name
+	Children []QName  `json:"children"`
+	Indexes  []string `json:"indexes"`
+	// Constraints are the names of the constraints on this table, ordered
+	// alphabetically.
+	Constraints []string `json:"constraints"`
+	// Options are the storage parameters, such as "fillfactor=70"
+	Options []string `json:"options"`
+
+	Owner string `json:"owner"`
+	// Privileges on the table. Column privileges are in Columns.
+	Privileges []Privilege `json:"privileges"`
+
+	// Definition is the query of a view or materialized view, as given by
+	// pg_get_viewdef()
+	Definition string `json:"definition"`
+	// CheckOption is "local" or "cascaded" for views created WITH CHECK
+	// OPTION
+	CheckOption     string `json:"check_option"`
+	SecurityBarrier bool   `json:"security_barrier"`
+	SecurityInvoker bool   `json:"security_invoker"`
+	// Populated is false for materialized views which have never been
+	// refreshed.
+	Populated bool `json:"populated"`
+	// ConcurrentRefreshIndexes are the unique indexes on a materialized view
+	// which make REFRESH MATERIALIZED VIEW CONCURRENTLY possible.
+	ConcurrentRefreshIndexes []string `json:"concurrent_refresh_indexes"`
+
+	// Triggers are the user defined triggers, by name
+	Triggers map[string]Trigger `json:"triggers"`
+
+	// RowSecurity is true after ALTER TABLE ... ENABLE ROW LEVEL SECURITY
+	RowSecurity bool `json:"row_security"`
+	// ForceRowSecurity is true if the policies also apply to the table
+	// owner
+	ForceRowSecurity bool `json:"force_row_security"`
+	// Policies are the row level security policies, by name
+	Policies map[string]Policy `json:"policies"`
+
+	// PartitionStrategy is "range", "list", or "hash" for partitioned
+	// tables
+	PartitionStrategy string `json:"partition_strategy"`
+	// PartitionKey has the column name or expression of every partition key
+	PartitionKey []string `json:"partition_key"`
+	// Partitions are the direct partitions of a partitioned table, ordered
+	// by schema and name. See Schema.PartitionTree() for all partitions.
+	Partitions []QName `json:"partitions"`
+	// DefaultPartition is the DEFAULT partition of a partitioned table
+	DefaultPartition QName `json:"default_partition"`
+	// PartitionOf is the parent of a partition. Partitions are not listed
+	// in Inherits/Children.
+	PartitionOf QName `json:"partition_of"`
+	// PartitionBound of a partition, such as "FOR VALUES IN ('ams')" or
+	// "DEFAULT"
+	PartitionBound string `json:"partition_bound"`
+	// DetachPending is true while a DETACH PARTITION CONCURRENTLY is in
+	// progress
+	DetachPending bool   `json:"detach_pending"`
+	Comment       string `json:"comment"`
 }
 
 type Column struct {
-	Type     string
-	NotNull  bool
-	Position int
+	// Type is the short name of the type, such as "varchar" or "int4[]"
+	Type string `json:"type"`
+	// TypeName is Type with the schema it lives in, such as
+	// {"pg_catalog", "int4"} or {"other", "mood"}. For arrays it's the
+	// element type.
+	TypeName QName `json:"type_name"`
+	// Array is set for array types, such as "int4[]"
+	Array bool `json:"array"`
+	// FullType is the type as PostgreSQL prints it, including type
+	// modifiers, such as "character varying(255)" or "integer[]"
+	FullType string `json:"full_type"`
+	NotNull  bool   `json:"not_null"`
+	// Position is the 1-based place of the column in the table, as used by
+	// `SELECT *`.
+	Position int `json:"position"`
+	// AttNum is PostgreSQL's internal column number. It has gaps where
+	// columns have been dropped.
+	AttNum int `json:"att_num"`
+	// Default is the DEFAULT expression, such as "now()". Empty if there is
+	// none.
+	Default string `json:"default"`
+	// Identity is "always" or "by default" for identity columns.
+	Identity string `json:"identity"`
+	// Generated is the expression of a GENERATED ALWAYS AS (...) STORED
+	// column.
+	Generated string `json:"generated"`
+	// Privileges are the column level privileges, if any
+	Privileges []Privilege `json:"privileges"`
+	Comment    string      `json:"comment"`
 }
 
 type Index struct {
-	Table   string
-	Type    string
-	Unique  bool
-	Primary bool
-	Columns []string // column name or '[function]' for expressions
+	Table   string `json:"table"`
+	Type    string `json:"type"`
+	Unique  bool   `json:"unique"`
+	Primary bool   `json:"primary"`
+	// Columns has the column name, or the expression, such as
+	// "lower((name)::text)", of every key
+	Columns []string `json:"columns"`
+	// Keys has the details of every entry in Columns
+	Keys []IndexKey `json:"keys"`
+	// Include are the non-key columns from an INCLUDE clause
+	Include []string `json:"include"`
+	// Predicate is the WHERE clause of a partial index, such as
+	// "(major > 0)"
+	Predicate string `json:"predicate"`
+	// Definition as given by pg_get_indexdef()
+	Definition string `json:"definition"`
+
+	// NullsNotDistinct is true for unique indexes created with NULLS NOT
+	// DISTINCT. Always false before PostgreSQL 15.
+	NullsNotDistinct bool `json:"nulls_not_distinct"`
+	// Immediate is false if uniqueness is only checked at the end of the
+	// transaction (a DEFERRABLE constraint)
+	Immediate bool `json:"immediate"`
+	Clustered bool `json:"clustered"`
+	// Valid is false after a failed CREATE INDEX CONCURRENTLY. Invalid
+	// indexes are not used for queries, and should be rebuilt.
+	Valid bool `json:"valid"`
+	// Ready is false while the index can't yet be used for inserts.
+	Ready bool `json:"ready"`
+	// Live is false while the index is being dropped.
+	Live bool `json:"live"`
+	// ReplicaIdentity is true for the REPLICA IDENTITY USING INDEX index.
+	ReplicaIdentity bool `json:"replica_identity"`
+	// Constraint is the name of the primary key, unique, or exclusion
+	// constraint this index implements, if any
+	Constraint string `json:"constraint"`
+
+	// Partitioned is true for an index on a partitioned table
+	Partitioned bool `json:"partitioned"`
+	// PartitionOf is the index on the partitioned table this index is a
+	// part of
+	PartitionOf QName  `json:"partition_of"`
+	Comment     string `json:"comment"`
+}
+
+// IndexKey is a single key of an index
+type IndexKey struct {
+	// Column is set for plain column keys, Expression for everything else
+	Column     string `json:"column"`
+	Expression string `json:"expression"`
+	Descending bool   `json:"descending"`
+	NullsFirst bool   `json:"nulls_first"`
+	// OpClass is only set if it's not the default for the type, such as
+	// "text_pattern_ops"
+	OpClass string `json:"op_class"`
+	// Collation is only set if it's not the database default
+	Collation string `json:"collation"`
+}
+
+// Constraint is a primary key, foreign key, unique, check, or exclusion
+// constraint on a table.
+type Constraint struct {
+	// Type is "primary key", "foreign key", "unique", "check", "exclusion",
+	// or "trigger"
+	Type    string   `json:"type"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Index is the index enforcing a primary key, unique, or exclusion
+	// constraint, or the referenced unique index of a foreign key. The
+	// referenced index lives in the schema of RefTable.
+	Index string `json:"index"`
+	// RefTable and RefColumns are only set for foreign keys.
+	RefTable   QName    `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	// OnUpdate and OnDelete are only set for foreign keys. They are one of
+	// "no action", "restrict", "cascade", "set null", or "set default".
+	OnUpdate          string `json:"on_update"`
+	OnDelete          string `json:"on_delete"`
+	Deferrable        bool   `json:"deferrable"`
+	InitiallyDeferred bool   `json:"initially_deferred"`
+	// Validated is false for constraints added with NOT VALID which have not
+	// been validated since.
+	Validated bool `json:"validated"`
+	// Definition as given by pg_get_constraintdef(), such as
+	// "CHECK ((minor > 0))"
+	Definition string `json:"definition"`
+	Comment    string `json:"comment"`
+}
+
+// Type is a user defined type.
+type Type struct {
+	// Type is "enum", "composite", "domain", or "range"
+	Type string `json:"type"`
+
+	// Labels of an enum, in order
+	Labels []string `json:"labels"`
+
+	// Attributes of a composite type
+	Attributes map[string]Column `json:"attributes"`
+
+	// BaseType of a domain, such as "character varying(10)"
+	BaseType string `json:"base_type"`
+	// Default of a domain, if any
+	Default string `json:"default"`
+	// NotNull is true for NOT NULL domains
+	NotNull bool `json:"not_null"`
+	// Constraints of a domain, by name
+	Constraints map[string]Constraint `json:"constraints"`
+
+	// Subtype of a range type, such as "int4"
+	Subtype string `json:"subtype"`
+	// Collation of a range type, if it's not the default
+	Collation string `json:"collation"`
+	// Canonical is the canonical function of a range type, if any
+	Canonical string `json:"canonical"`
+	// SubtypeDiff is the subtype_diff function of a range type, if any
+	SubtypeDiff string `json:"subtype_diff"`
+	// Multirange is the name of the multirange type of a range type.
+	// Always empty before PostgreSQL 14.
+	Multirange string `json:"multirange"`
+
+	Owner      string      `json:"owner"`
+	Privileges []Privilege `json:"privileges"`
+	Comment    string      `json:"comment"`
+}
+
+// Trigger is a trigger on a table or view.
+type Trigger struct {
+	// Timing is "before", "after", or "instead of"
+	Timing string `json:"timing"`
+	// Events are one or more of "insert", "update", "delete", and
+	// "truncate"
+	Events []string `json:"events"`
+	// UpdateColumns are the columns of an UPDATE OF trigger
+	UpdateColumns []string `json:"update_columns"`
+	// Level is "row" or "statement"
+	Level string `json:"level"`
+	// When is the WHEN condition, if any
+	When string `json:"when"`
+	// OldTable and NewTable are the REFERENCING transition table names
+	OldTable string `json:"old_table"`
+	NewTable string `json:"new_table"`
+	// Enabled is "origin" (the default), "always", "replica", or
+	// "disabled". See ALTER TABLE ... ENABLE TRIGGER.
+	Enabled string `json:"enabled"`
+	// Constraint is true for CREATE CONSTRAINT TRIGGER triggers
+	Constraint bool `json:"constraint"`
+	// Function is the called function, such as "audit"
+	Function  string   `json:"function"`
+	Arguments []string `json:"arguments"`
+	// Definition as given by pg_get_triggerdef()
+	Definition string `json:"definition"`
+	Comment    string `json:"comment"`
+}
+
+// Policy is a row level security policy.
+type Policy struct {
+	// Command is "all", "select", "insert", "update", or "delete"
+	Command string `json:"command"`
+	// Permissive is false for AS RESTRICTIVE policies
+	Permissive bool `json:"permissive"`
+	// Roles the policy applies to, ordered alphabetically. "public" for
+	// PUBLIC.
+	Roles []string `json:"roles"`
+	// Using is the USING expression, if any
+	Using string `json:"using"`
+	// WithCheck is the WITH CHECK expression, if any
+	WithCheck string `json:"with_check"`
+	Comment   string `json:"comment"`
+}
+
+// EventTrigger is a database wide trigger on DDL events.
+type EventTrigger struct {
+	// Event is "ddl_command_start", "ddl_command_end", "table_rewrite",
+	// or "sql_drop"
+	Event string `json:"event"`
+	// Tags are the command tags the trigger is limited to, if any
+	Tags     []string `json:"tags"`
+	Function string   `json:"function"`
+	// Enabled is "origin", "always", "replica", or "disabled"
+	Enabled string `json:"enabled"`
+	Comment string `json:"comment"`
 }
 
 type Sequence struct {
-	IncrementBy int
-	MinValue    int
-	MaxValue    int
-	Start       int
-	Cycle       bool
+	// Type is "smallint", "integer", or "bigint"
+	Type        string `json:"type"`
+	IncrementBy int    `json:"increment_by"`
+	MinValue    int    `json:"min_value"`
+	MaxValue    int    `json:"max_value"`
+	Start       int    `json:"start"`
+	Cache       int    `json:"cache"`
+	Cycle       bool   `json:"cycle"`
+	// LastValue is what the sequence last returned. Only set when IsCalled
+	// is true.
+	LastValue int `json:"last_value"`
+	// IsCalled is false if nextval() was never called, or if we don't have
+	// the privileges to read the sequence
+	IsCalled bool `json:"is_called"`
+	// OwnedByTable and OwnedByColumn are set for sequences which are OWNED
+	// BY a column, such as the sequences of serial and identity columns
+	OwnedByTable  string      `json:"owned_by_table"`
+	OwnedByColumn string      `json:"owned_by_column"`
+	Owner         string      `json:"owner"`
+	Privileges    []Privilege `json:"privileges"`
+	Comment       string      `json:"comment"`
 }
 
 type Function struct {
-	Language      string
-	ArgumentTypes []string
-	Src           string
-}
+	Name string `json:"name"`
+	// Kind is "function", "procedure", "aggregate", or "window"
+	Kind     string `json:"kind"`
+	Language string `json:"language"`
+	// ArgumentTypes are the types of the input arguments, which make up
+	// the signature.
+	ArgumentTypes []string `json:"argument_types"`
+	// Arguments are all arguments, including OUT and TABLE arguments.
+	Arguments []Argument `json:"arguments"`
+	// ReturnType is the type name, such as "int4". See Result for the full
+	// description.
+	ReturnType string `json:"return_type"`
+	ReturnsSet bool   `json:"returns_set"`
+	// Result as given by pg_get_function_result(), such as "SETOF integer"
+	// or "TABLE(id integer, name text)"
+	Result string `json:"result"`
+	// Volatility is "immutable", "stable", or "volatile"
+	Volatility string `json:"volatility"`
+	Strict     bool   `json:"strict"`
+	// Parallel is "safe", "restricted", or "unsafe"
+	Parallel        string  `json:"parallel"`
+	Leakproof       bool    `json:"leakproof"`
+	SecurityDefiner bool    `json:"security_definer"`
+	Cost            float64 `json:"cost"`
+	Rows            float64 `json:"rows"`
+	// Config are the SET options, such as "search_path=public"
+	Config []string `json:"config"`
+	Src    string   `json:"src"`
+	// Definition as given by pg_get_functiondef(). Empty for aggregates.
+	Definition string `json:"definition"`
 
-// Public is a wrapper around Describe. It needs a pg URL (such as
-// "postgres://localhost"), and it'll return the public schema.
-func Public(pgURL string) (*Schema, error) {
-	cc, err := pgx.ParseURI(pgURL)
-	if err != nil {
-		return nil, err
-	}
-	db, err := pgx.NewConnPool(pgx.ConnPoolConfig{
-		ConnConfig: cc,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return Describe(db, "public")
+	Owner      string      `json:"owner"`
+	Privileges []Privilege `json:"privileges"`
+	Comment    string      `json:"comment"`
 }
 
-// Describe a schema. Leave schema empty for the public schema.
-func Describe(conn *pgx.ConnPool, schema string) (*Schema, error) {
-	tx, err := conn.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-	return DescribeTx(tx, schema)
+// Argument is a function argument.
+type Argument struct {
+	// Name is empty for unnamed arguments
+	Name string `json:"name"`
+	// Mode is "in", "out", "inout", "variadic", or "table"
+	Mode     string `json:"mode"`
+	Type     string `json:"type"`
+	TypeName QName  `json:"type_name"` // Type, with its schema. See Column.TypeName.
+	Array    bool   `json:"array"`
+	// Default is the DEFAULT expression, if any
+	Default string `json:"default"`
 }
 
 // Describe a schema. Leave schema empty for the public schema.
-func DescribeConn(conn *pgx.Conn, schema string) (*Schema, error) {
-	tx, err := conn.Begin()
+//
+// All queries are done with q, which should be a transaction to get a
+// consistent result. See DescribeContext() and the pgxv5 package for helpers
+// which start one. Returns a *TimeoutError when the deadline of the context
+// is exceeded, or when a statement_timeout or lock_timeout is hit.
+func Describe(ctx context.Context, q Queryer, schema string) (*Schema, error) {
+	d, err := describeSchema(ctx, q, schema)
 	if err != nil {
-		return nil, err
+		return nil, asTimeout(ctx, err)
 	}
-	defer tx.Rollback()
-	return DescribeTx(tx, schema)
+	return d, nil
 }
 
-// Describe a schema. Leave schema empty for the public schema.
-func DescribeTx(tx *pgx.Tx, schema string) (*Schema, error) {
+func describeSchema(ctx context.Context, q Queryer, schema string) (*Schema, error) {
 	if schema == "" {
 		schema = "public"
 	}
 
-	dbs, err := pgNamespace(tx)
+	dbs, err := pgNamespace(ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -127,30 +455,54 @@ func DescribeTx(tx *pgx.Tx, schema string) (*Schema, error) {
 	if !ok {
 		return nil, fmt.Errorf("schema %q not found in pg_catalog", schema)
 	}
-	oids, err := loadSchema(tx, db.OID)
+	catalog, err := loadCatalog(ctx, q, dbs)
 	if err != nil {
 		return nil, err
 	}
+	oids, err := catalog.loadNamespace(ctx, q, db.OID)
+	if err != nil {
+		return nil, err
+	}
+	return describe(oids, db), nil
+}
 
+// describe builds the Schema from the catalogs of its namespace
+func describe(oids *_OIDs, db schemaNamespace) *Schema {
 	d := &Schema{
-		Name:      db.NspName,
-		Relations: map[string]Relation{},
-		Indexes:   map[string]Index{},
-		Sequences: map[string]Sequence{},
-		Functions: map[string]Function{},
+		Name:          db.NspName,
+		Owner:         db.NspOwner,
+		Privileges:    parseACL(db.NspACL),
+		Comment:       oids.comment("pg_namespace", db.OID, 0),
+		Relations:     map[string]Relation{},
+		Indexes:       map[string]Index{},
+		Sequences:     map[string]Sequence{},
+		Constraints:   map[string]map[string]Constraint{},
+		Types:         map[string]Type{},
+		EventTriggers: map[string]EventTrigger{},
+		Functions:     map[string]Function{},
 	}
 	d.addRelations(oids)
 	d.addInherits(oids)
 	d.addColumns(oids)
 	d.addIndexes(oids)
-	d.addSequences(tx, oids)
+	d.addConstraints(oids)
+	d.addTriggers(oids)
+	d.addEventTriggers(oids)
+	d.addPolicies(oids)
+	d.addSequences(oids)
+	d.addTypes(oids, db.OID)
 	d.addFunctions(oids)
+	return d
+}
 
-	return d, nil
+var partitionStrategies = map[string]string{
+	"r": "range",
+	"l": "list",
+	"h": "hash",
 }
 
 func (s *Schema) addRelations(oids *_OIDs) {
-	for _, st := range oids.class {
+	for oid, st := range oids.class {
 		r := Relation{
 			Columns: map[string]Column{},
 		}
@@ -161,196 +513,891 @@ func (s *Schema) addRelations(oids *_OIDs) {
 			sort.Strings(s.Tables)
 		case "v":
 			r.Type = "view"
+			r.Definition = st.ViewDef
 			s.Views = append(s.Views, st.RelName)
 			sort.Strings(s.Views)
 		case "m":
 			r.Type = "materialized view"
+			r.Definition = st.ViewDef
+			r.Populated = st.RelIsPopulated
 			s.Materialized = append(s.Materialized, st.RelName)
 			sort.Strings(s.Materialized)
+		case "p":
+			r.Type = "partitioned table"
+			pt := oids.partitioned[oid]
+			r.PartitionStrategy = partitionStrategies[pt.PartStrat]
+			r.PartitionKey = partitionKey(pt.PartKeyDef)
+			r.DefaultPartition = oids.relName(pt.PartDefID)
+			s.Partitioned = append(s.Partitioned, st.RelName)
+			sort.Strings(s.Partitioned)
 		case "S":
 			// sequence, handled in addSequences()
 			continue
 		default:
 			continue
 		}
+		if st.RelIsPartition {
+			r.PartitionBound = st.PartBound
+		}
+		r.Comment = oids.comment("pg_class", oid, 0)
+		r.Owner = st.RelOwner
+		r.Privileges = parseACL(st.RelACL)
+		r.RowSecurity = st.RelRowSecurity
+		r.ForceRowSecurity = st.RelForceRowSecurity
+		r.Options = st.RelOptions
+		r.CheckOption = relOption(st.RelOptions, "check_option")
+		r.SecurityBarrier = isTrue(relOption(st.RelOptions, "security_barrier"))
+		r.SecurityInvoker = isTrue(relOption(st.RelOptions, "security_invoker"))
 		s.Relations[st.RelName] = r
 	}
 }
 
+// partitionKey gets the keys from a pg_get_partkeydef() string, such as
+// "RANGE (logdate, lower(city))"
+func partitionKey(def string) []string {
+	start, end := strings.Index(def, "("), strings.LastIndex(def, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	return splitTopLevel(def[start+1:end], ',')
+}
+
+// relOption finds the value of a "key=value" pg_class.reloptions entry.
+func relOption(options []string, key string) string {
+	for _, o := range options {
+		if strings.HasPrefix(o, key+"=") {
+			return o[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// isTrue interprets a PostgreSQL boolean setting
+func isTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "on", "yes", "1", "t", "y":
+		return true
+	default:
+		return false
+	}
+}
+
+// addInherits sets the inheritance and partitioning relations. Either side
+// can be in another namespace.
 func (s *Schema) addInherits(oids *_OIDs) {
-	for _, e := range oids.inherits {
-		childO, ok := oids.class[e.InhRelID]
-		if !ok {
+	inherits := append([]schemaInherits(nil), oids.inherits...)
+	sort.Slice(inherits, func(i, j int) bool {
+		return inherits[i].InhSeqNo < inherits[j].InhSeqNo
+	})
+	for _, e := range inherits {
+		childO, childOK := oids.class[e.InhRelID]
+		parentO, parentOK := oids.class[e.InhParent]
+		if !childOK && !parentOK {
 			continue
 		}
-		childTable := childO.RelName
-		parentO, ok := oids.class[e.InhParent]
-		if !ok {
+		if k := oids.classNames[e.InhRelID].RelKind; k == "i" || k == "I" {
+			// index partitions, handled in addIndexes()
+			continue
+		}
+		var (
+			childName  = oids.relName(e.InhRelID)
+			parentName = oids.relName(e.InhParent)
+		)
+		if oids.classNames[e.InhRelID].RelIsPartition {
+			if childOK {
+				child := s.Relations[childO.RelName]
+				child.PartitionOf = parentName
+				child.DetachPending = e.InhDetachPending
+				s.Relations[childO.RelName] = child
+			}
+			if parentOK {
+				parent := s.Relations[parentO.RelName]
+				parent.Partitions = append(parent.Partitions, childName)
+				sortQNames(parent.Partitions)
+				s.Relations[parentO.RelName] = parent
+			}
 			continue
 		}
-		parentTable := parentO.RelName
-		child := s.Relations[childTable]
-		child.Inherits = append(child.Inherits, parentTable)
-		s.Relations[childTable] = child
-		parent := s.Relations[parentTable]
-		parent.Children = append(parent.Children, childTable)
-		s.Relations[parentTable] = parent
+		if childOK {
+			child := s.Relations[childO.RelName]
+			child.Inherits = append(child.Inherits, parentName)
+			s.Relations[childO.RelName] = child
+		}
+		if parentOK {
+			parent := s.Relations[parentO.RelName]
+			parent.Children = append(parent.Children, childName)
+			sortQNames(parent.Children)
+			s.Relations[parentO.RelName] = parent
+		}
 	}
 }
 
 func (s *Schema) addColumns(oids *_OIDs) {
-	for _, ct := range oids.attribute {
-		if ct.AttNum < 0 {
-			// system column
+	for oid, cl := range oids.class {
+		rel, ok := s.Relations[cl.RelName]
+		if !ok {
 			continue
 		}
+		rel.Columns = oids.columns(oid)
+		s.Relations[cl.RelName] = rel
+	}
+}
 
-		cl, ok := oids.class[ct.AttRelID]
-		if !ok {
+// columns gives the columns of a relation or composite type
+func (db *_OIDs) columns(rel objectID) map[string]Column {
+	var (
+		cols  = map[string]Column{}
+		names []string
+	)
+	for _, ct := range db.attributes[rel] {
+		if ct.AttNum < 0 {
+			// system column
 			continue
 		}
-		rel, ok := s.Relations[cl.RelName]
-		if !ok {
+		if ct.AttIsDropped {
 			continue
 		}
-		rel.Columns[ct.AttName] = Column{
-			Type:     oids.typeName(ct.AttTypID),
-			NotNull:  ct.AttNotNull,
-			Position: ct.AttNum,
+
+		c := Column{
+			Type:       db.typeName(ct.AttTypID),
+			TypeName:   db.typeQName(ct.AttTypID),
+			Array:      db.typ[ct.AttTypID].isArray(),
+			FullType:   ct.FormatType,
+			NotNull:    ct.AttNotNull,
+			AttNum:     ct.AttNum,
+			Identity:   identityKinds[ct.AttIdentity],
+			Privileges: parseACL(ct.AttACL),
+			Comment:    db.comment("pg_class", rel, ct.AttNum),
 		}
-		s.Relations[cl.RelName] = rel
+		def := db.attrdef[ct.AttRelID][ct.AttNum]
+		if ct.AttGenerated != "" {
+			c.Generated = def
+		} else {
+			c.Default = def
+		}
+		cols[ct.AttName] = c
+		names = append(names, ct.AttName)
+	}
+
+	// attnums have gaps after a DROP COLUMN, positions don't
+	sort.Slice(names, func(i, j int) bool {
+		return cols[names[i]].AttNum < cols[names[j]].AttNum
+	})
+	for i, n := range names {
+		c := cols[n]
+		c.Position = i + 1
+		cols[n] = c
 	}
+	return cols
 }
 
+var identityKinds = map[string]string{
+	"a": "always",
+	"d": "by default",
+}
+
+const (
+	// pg_index.indoption bits
+	indoptionDesc       = 0x01
+	indoptionNullsFirst = 0x02
+
+	defaultCollation objectID = 100
+)
+
 func (s *Schema) addIndexes(oids *_OIDs) {
 	// indexes columns are split over pg_class 'i' records, and over pg_index
 	for tOid, st := range oids.class {
 		switch st.RelKind {
-		case "i": // index
+		case "i", "I": // index, partitioned index
 			index := oids.index[tOid]
 			relName := oids.class[index.IndRelID].RelName
 			rel := s.Relations[relName]
 
-			var cols []string
-			for _, i := range index.IndKey {
-				if i == 0 {
-					// TODO: indexprs could be used to render the function
-					cols = append(cols, "[function]")
+			var (
+				cols        []string
+				keys        []IndexKey
+				include     []string
+				expressions bool
+			)
+			for n, i := range index.IndKey {
+				if n >= index.IndNKeyAtts {
+					include = append(include, oids.attName(index.IndRelID, int(i)))
 					continue
 				}
-				cols = append(cols, rel.ColumnNames()[i-1])
+				k := IndexKey{
+					Descending: index.IndOption[n]&indoptionDesc != 0,
+					NullsFirst: index.IndOption[n]&indoptionNullsFirst != 0,
+				}
+				if i == 0 {
+					expressions = true
+					k.Expression = index.KeyDefs[n]
+					cols = append(cols, k.Expression)
+				} else {
+					k.Column = oids.attName(index.IndRelID, int(i))
+					cols = append(cols, k.Column)
+				}
+				if oc := oids.opclass[index.IndClass[n]]; !oc.OpcDefault {
+					k.OpClass = oc.OpcName
+				}
+				if c := index.IndCollation[n]; c != 0 && c != defaultCollation {
+					k.Collation = oids.collation[c].CollName
+				}
+				keys = append(keys, k)
 			}
 			s.Indexes[st.RelName] = Index{
-				Table:   relName,
-				Type:    oids.am[st.RelAm].AmName,
-				Unique:  index.IndIsUnique,
-				Primary: index.IndIsPrimary,
-				Columns: cols,
+				Table:            relName,
+				Type:             oids.am[st.RelAm].AmName,
+				Unique:           index.IndIsUnique,
+				Primary:          index.IndIsPrimary,
+				Columns:          cols,
+				Keys:             keys,
+				Include:          include,
+				Predicate:        index.IndPred,
+				Definition:       index.IndexDef,
+				NullsNotDistinct: index.IndNullsNotDistinct,
+				Immediate:        index.IndImmediate,
+				Clustered:        index.IndIsClustered,
+				Valid:            index.IndIsValid,
+				Ready:            index.IndIsReady,
+				Live:             index.IndIsLive,
+				ReplicaIdentity:  index.IndIsReplIdent,
+				Constraint:       oids.indexConstraint(tOid),
+				Comment:          oids.comment("pg_class", tOid, 0),
+				Partitioned:      st.RelKind == "I",
+				PartitionOf:      oids.relName(oids.parent(tOid)),
 			}
 
 			rel.Indexes = append(rel.Indexes, st.RelName)
 			sort.Strings(rel.Indexes)
+			if rel.Type == "materialized view" && index.IndIsUnique && !expressions && index.IndPred == "" {
+				rel.ConcurrentRefreshIndexes = append(rel.ConcurrentRefreshIndexes, st.RelName)
+				sort.Strings(rel.ConcurrentRefreshIndexes)
+			}
 			s.Relations[relName] = rel
 		}
 	}
 }
 
-func (s *Schema) addSequences(tx *pgx.Tx, oids *_OIDs) error {
-	for _, st := range oids.class {
-		switch st.RelKind {
-		case "S":
-			seq, err := loadSequence(tx, s.Name, st.RelName)
-			if err != nil {
-				return err
+var (
+	constraintTypes = map[string]string{
+		"p": "primary key",
+		"f": "foreign key",
+		"u": "unique",
+		"c": "check",
+		"x": "exclusion",
+		"t": "trigger",
+	}
+	foreignKeyActions = map[string]string{
+		"a": "no action",
+		"r": "restrict",
+		"c": "cascade",
+		"n": "set null",
+		"d": "set default",
+	}
+)
+
+func (s *Schema) addConstraints(oids *_OIDs) {
+	for oid, e := range oids.constraint {
+		if e.ConRelID == 0 {
+			// not a table constraint
+			continue
+		}
+		relName := oids.class[e.ConRelID].RelName
+		rel, ok := s.Relations[relName]
+		if !ok {
+			continue
+		}
+
+		c := Constraint{
+			Type:              constraintTypes[e.ConType],
+			Table:             relName,
+			Index:             oids.class[e.ConIndID].RelName,
+			Deferrable:        e.ConDeferrable,
+			InitiallyDeferred: e.ConDeferred,
+			Validated:         e.ConValidated,
+			Definition:        e.ConstraintDef,
+			Comment:           oids.comment("pg_constraint", oid, 0),
+		}
+		for _, k := range e.ConKey {
+			c.Columns = append(c.Columns, oids.attName(e.ConRelID, int(k)))
+		}
+		if e.ConType == "f" {
+			c.RefTable = oids.relName(e.ConFRelID)
+			for _, k := range e.ConFKey {
+				c.RefColumns = append(c.RefColumns, oids.attName(e.ConFRelID, int(k)))
+			}
+			c.OnUpdate = foreignKeyActions[e.ConFUpdType]
+			c.OnDelete = foreignKeyActions[e.ConFDelType]
+		}
+		if s.Constraints[relName] == nil {
+			s.Constraints[relName] = map[string]Constraint{}
+		}
+		s.Constraints[relName][e.ConName] = c
+
+		rel.Constraints = append(rel.Constraints, e.ConName)
+		sort.Strings(rel.Constraints)
+		s.Relations[relName] = rel
+	}
+}
+
+const (
+	// pg_trigger.tgtype bits
+	triggerTypeRow      = 1 << 0
+	triggerTypeBefore   = 1 << 1
+	triggerTypeInsert   = 1 << 2
+	triggerTypeDelete   = 1 << 3
+	triggerTypeUpdate   = 1 << 4
+	triggerTypeTruncate = 1 << 5
+	triggerTypeInstead  = 1 << 6
+)
+
+var triggerEnabled = map[string]string{
+	"O": "origin",
+	"A": "always",
+	"R": "replica",
+	"D": "disabled",
+}
+
+func (s *Schema) addTriggers(oids *_OIDs) {
+	for _, e := range oids.trigger {
+		relName := oids.class[e.TgRelID].RelName
+		rel, ok := s.Relations[relName]
+		if !ok {
+			continue
+		}
+
+		t := Trigger{
+			Timing:     "after",
+			Level:      "statement",
+			When:       triggerWhen(e.TriggerDef),
+			OldTable:   e.TgOldTable,
+			NewTable:   e.TgNewTable,
+			Enabled:    triggerEnabled[e.TgEnabled],
+			Constraint: e.TgConstraint,
+			Function:   e.TgFunction,
+			Definition: e.TriggerDef,
+			Comment:    oids.comment("pg_trigger", e.OID, 0),
+		}
+		switch {
+		case e.TgType&triggerTypeBefore != 0:
+			t.Timing = "before"
+		case e.TgType&triggerTypeInstead != 0:
+			t.Timing = "instead of"
+		}
+		if e.TgType&triggerTypeRow != 0 {
+			t.Level = "row"
+		}
+		for _, ev := range []struct {
+			bit  int
+			name string
+		}{
+			{triggerTypeInsert, "insert"},
+			{triggerTypeUpdate, "update"},
+			{triggerTypeDelete, "delete"},
+			{triggerTypeTruncate, "truncate"},
+		} {
+			if e.TgType&ev.bit != 0 {
+				t.Events = append(t.Events, ev.name)
+			}
+		}
+		for _, a := range e.TgAttr {
+			t.UpdateColumns = append(t.UpdateColumns, oids.attName(e.TgRelID, int(a)))
+		}
+		if len(e.TgArgs) > 0 {
+			// every argument is \0 terminated
+			t.Arguments = strings.Split(strings.TrimSuffix(string(e.TgArgs), "\x00"), "\x00")
+		}
+
+		if rel.Triggers == nil {
+			rel.Triggers = map[string]Trigger{}
+		}
+		rel.Triggers[e.TgName] = t
+		s.Relations[relName] = rel
+	}
+}
+
+// triggerWhen gets the WHEN condition from a pg_get_triggerdef() string.
+// pg_get_expr() can't be used on tgqual. Quoted names and strings can have
+// anything in them, so they are skipped.
+func triggerWhen(def string) string {
+	var (
+		quote byte
+		start = -1
+		depth int
+	)
+	for i := 0; i < len(def); i++ {
+		c := def[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case start < 0:
+			if strings.HasPrefix(def[i:], " WHEN (") {
+				start = i + len(" WHEN (")
+				depth = 1
+				i = start - 1
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return def[start:i]
+			}
+		}
+	}
+	return ""
+}
+
+var policyCommands = map[string]string{
+	"*": "all",
+	"r": "select",
+	"a": "insert",
+	"w": "update",
+	"d": "delete",
+}
+
+func (s *Schema) addPolicies(oids *_OIDs) {
+	for _, e := range oids.policy {
+		relName := oids.class[e.PolRelID].RelName
+		rel, ok := s.Relations[relName]
+		if !ok {
+			continue
+		}
+		roles := append([]string(nil), e.PolRoles...)
+		sort.Strings(roles)
+		if rel.Policies == nil {
+			rel.Policies = map[string]Policy{}
+		}
+		rel.Policies[e.PolName] = Policy{
+			Command:    policyCommands[e.PolCmd],
+			Permissive: e.PolPermissive,
+			Roles:      roles,
+			Using:      e.PolQual,
+			WithCheck:  e.PolWithCheck,
+			Comment:    oids.comment("pg_policy", e.OID, 0),
+		}
+		s.Relations[relName] = rel
+	}
+}
+
+func (s *Schema) addEventTriggers(oids *_OIDs) {
+	for _, e := range oids.eventTrigger {
+		s.EventTriggers[e.EvtName] = EventTrigger{
+			Event:    e.EvtEvent,
+			Tags:     e.EvtTags,
+			Function: e.EvtFunction,
+			Enabled:  triggerEnabled[e.EvtEnabled],
+			Comment:  oids.comment("pg_event_trigger", e.OID, 0),
+		}
+	}
+}
+
+func (s *Schema) addSequences(oids *_OIDs) {
+	for oid, st := range oids.class {
+		if st.RelKind != "S" {
+			continue
+		}
+		e := oids.sequence[oid]
+		seq := Sequence{
+			Type:        e.SeqType,
+			IncrementBy: e.SeqIncrement,
+			MinValue:    e.SeqMin,
+			MaxValue:    e.SeqMax,
+			Start:       e.SeqStart,
+			Cache:       e.SeqCache,
+			Cycle:       e.SeqCycle,
+			LastValue:   e.LastValue,
+			IsCalled:    e.IsCalled,
+			Owner:       st.RelOwner,
+			Privileges:  parseACL(st.RelACL),
+			Comment:     oids.comment("pg_class", oid, 0),
+		}
+		if t, ok := oids.class[e.OwnedBy]; ok {
+			seq.OwnedByTable = t.RelName
+			seq.OwnedByColumn = oids.attName(e.OwnedBy, e.OwnedByCol)
+		}
+		s.Sequences[st.RelName] = seq
+	}
+}
+
+var typeTypes = map[string]string{
+	"e": "enum",
+	"c": "composite",
+	"d": "domain",
+	"r": "range",
+}
+
+func (s *Schema) addTypes(oids *_OIDs, namespace objectID) {
+	for oid, t := range oids.typ {
+		if t.TypNamespace != namespace {
+			continue
+		}
+		ty := Type{
+			Type:       typeTypes[t.TypType],
+			Owner:      t.TypOwner,
+			Privileges: parseACL(t.TypACL),
+			Comment:    oids.comment("pg_type", oid, 0),
+		}
+		switch t.TypType {
+		case "e":
+			ty.Labels = oids.enum[oid]
+		case "c":
+			if oids.class[t.TypRelID].RelKind != "c" {
+				// the row type of a table or view
+				continue
 			}
-			s.Sequences[st.RelName] = seq
+			ty.Attributes = oids.columns(t.TypRelID)
+		case "d":
+			ty.BaseType = t.BaseType
+			ty.Default = t.TypDefault
+			ty.NotNull = t.TypNotNull
+			ty.Constraints = map[string]Constraint{}
+			for coid, c := range oids.constraint {
+				if c.ConTypID != oid {
+					continue
+				}
+				ty.Constraints[c.ConName] = Constraint{
+					Type:       constraintTypes[c.ConType],
+					Validated:  c.ConValidated,
+					Definition: c.ConstraintDef,
+					Comment:    oids.comment("pg_constraint", coid, 0),
+				}
+			}
+		case "r":
+			r := oids.rng[oid]
+			ty.Subtype = oids.typeName(r.RngSubtype)
+			if r.RngCollation != 0 && r.RngCollation != defaultCollation {
+				ty.Collation = oids.collation[r.RngCollation].CollName
+			}
+			ty.Canonical = r.RngCanonical
+			ty.SubtypeDiff = r.RngSubDiff
+			ty.Multirange = oids.typ[r.RngMultiTyp].TypName
 		default:
 			continue
 		}
+		s.Types[t.TypName] = ty
 	}
-	return nil
 }
 
+var (
+	functionKinds = map[string]string{
+		"f": "function",
+		"p": "procedure",
+		"a": "aggregate",
+		"w": "window",
+	}
+	argumentModes = map[string]string{
+		"i": "in",
+		"o": "out",
+		"b": "inout",
+		"v": "variadic",
+		"t": "table",
+	}
+	volatilities = map[string]string{
+		"i": "immutable",
+		"s": "stable",
+		"v": "volatile",
+	}
+	parallelSafeties = map[string]string{
+		"s": "safe",
+		"r": "restricted",
+		"u": "unsafe",
+	}
+)
+
 func (s *Schema) addFunctions(oids *_OIDs) {
-	for _, e := range oids.proc {
+	for oid, e := range oids.proc {
 		l, ok := oids.language[e.ProLang]
 		if !ok {
 			continue
 		}
 		f := Function{
-			Language: l.LanName,
-			Src:      e.ProSrc,
+			Name:            e.ProName,
+			Kind:            functionKinds[e.ProKind],
+			Language:        l.LanName,
+			ReturnType:      oids.typeName(e.ProRetType),
+			ReturnsSet:      e.ProRetSet,
+			Result:          e.FunctionResult,
+			Volatility:      volatilities[e.ProVolatile],
+			Strict:          e.ProIsStrict,
+			Parallel:        parallelSafeties[e.ProParallel],
+			Leakproof:       e.ProLeakproof,
+			SecurityDefiner: e.ProSecDef,
+			Cost:            e.ProCost,
+			Rows:            e.ProRows,
+			Config:          e.ProConfig,
+			Src:             e.ProSrc,
+			Definition:      e.FunctionDef,
+			Owner:           e.ProOwner,
+			Privileges:      parseACL(e.ProACL),
+			Comment:         oids.comment("pg_proc", oid, 0),
 		}
 		for _, t := range e.ProArgTypes {
 			f.ArgumentTypes = append(f.ArgumentTypes, oids.typeName(t))
 		}
-		s.Functions[e.ProName] = f
+		f.Arguments = oids.arguments(e)
+		s.Functions[f.Signature()] = f
+	}
+}
+
+// arguments combines the various pg_proc argument arrays. The
+// pronargdefaults defaults are for the last input arguments.
+func (db *_OIDs) arguments(e schemaProc) []Argument {
+	types := e.ProAllArgTypes
+	if types == nil {
+		types = e.ProArgTypes
+	}
+	defaults := splitTopLevel(e.ProArgDefaults, ',')
+	var (
+		args   []Argument
+		inputs []int // index in args
+	)
+	for i, t := range types {
+		a := Argument{
+			Mode:     "in",
+			Type:     db.typeName(t),
+			TypeName: db.typeQName(t),
+			Array:    db.typ[t].isArray(),
+		}
+		if e.ProArgModes != nil {
+			a.Mode = argumentModes[e.ProArgModes[i]]
+		}
+		if i < len(e.ProArgNames) {
+			a.Name = e.ProArgNames[i]
+		}
+		switch a.Mode {
+		case "in", "inout", "variadic":
+			inputs = append(inputs, i)
+		}
+		args = append(args, a)
+	}
+	if len(defaults) == e.ProNArgDefaults && len(defaults) <= len(inputs) {
+		for i, d := range defaults {
+			args[inputs[len(inputs)-len(defaults)+i]].Default = d
+		}
+	}
+	return args
+}
+
+// splitTopLevel splits on sep, but not when it's in parenthesis or quotes.
+// Parts are trimmed.
+func splitTopLevel(s string, sep rune) []string {
+	var (
+		parts []string
+		depth int
+		quote rune
+		start int
+	)
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == sep && depth == 0:
+			if p := strings.TrimSpace(s[start:i]); p != "" {
+				parts = append(parts, p)
+			}
+			start = i + 1
+		}
+	}
+	if p := strings.TrimSpace(s[start:]); p != "" {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// PartitionTree lists all partitions of a partitioned table, including the
+// partitions of partitions, depth first. Partitions in other schemas are
+// listed, but their partitions are not.
+func (s *Schema) PartitionTree(table string) []QName {
+	var res []QName
+	for _, p := range s.Relations[table].Partitions {
+		res = append(res, p)
+		if p.Schema == s.Name {
+			res = append(res, s.PartitionTree(p.Name)...)
+		}
+	}
+	return res
+}
+
+// FunctionsByName gives the signatures of all overloads of a function,
+// ordered alphabetically. These are the keys in Functions.
+func (s *Schema) FunctionsByName(name string) []string {
+	var sigs []string
+	for sig, f := range s.Functions {
+		if f.Name == name {
+			sigs = append(sigs, sig)
+		}
 	}
+	sort.Strings(sigs)
+	return sigs
+}
+
+// Signature is the name with the argument types, such as "add(int4,int4)".
+func (f *Function) Signature() string {
+	return f.Name + "(" + strings.Join(f.ArgumentTypes, ",") + ")"
 }
 
 // ColumnNames lists all columns in database order
 func (t *Relation) ColumnNames() []string {
-	var names = make([]string, len(t.Columns))
-	for c, d := range t.Columns {
-		names[d.Position-1] = c
+	var names = make([]string, 0, len(t.Columns))
+	for c := range t.Columns {
+		names = append(names, c)
 	}
+	sort.Slice(names, func(i, j int) bool {
+		return t.Columns[names[i]].Position < t.Columns[names[j]].Position
+	})
 	return names
 }
 
+type descriptionKey struct {
+	catalog string
+	oid     objectID
+	sub     int
+}
+
 // _OIDs has all the info from the pg_catalog tables in raw format
 type _OIDs struct {
-	class     map[pgx.Oid]schemaClass
-	typ       map[pgx.Oid]schemaType
-	inherits  []schemaInherits
-	attribute []schemaAttribute
-	index     map[pgx.Oid]schemaIndex
-	am        map[pgx.Oid]schemaAm
-	proc      map[pgx.Oid]schemaProc
-	language  map[pgx.Oid]schemaLanguage
+	version      int
+	namespaces   map[objectID]string
+	classNames   map[objectID]schemaClassName // every namespace
+	typ          map[objectID]schemaType
+	enum         map[objectID][]string // labels, in order
+	rng          map[objectID]schemaRange
+	inherits     []schemaInherits
+	partitioned  map[objectID]schemaPartitionedTable
+	attributes   map[objectID][]schemaAttribute // by relation
+	attrdef      map[objectID]map[int]string    // relation -> attnum -> expression
+	index        map[objectID]schemaIndex
+	am           map[objectID]schemaAm
+	opclass      map[objectID]schemaOpClass
+	collation    map[objectID]schemaCollation
+	language     map[objectID]schemaLanguage
+	eventTrigger []schemaEventTrigger
+
+	// only for a single namespace, see loadNamespace()
+	class       map[objectID]schemaClass
+	proc        map[objectID]schemaProc
+	constraint  map[objectID]schemaConstraint
+	sequence    map[objectID]schemaSequence
+	trigger     []schemaTrigger
+	policy      []schemaPolicy
+	description map[descriptionKey]string
 }
 
-func loadSchema(tx *pgx.Tx, schema pgx.Oid) (*_OIDs, error) {
+// loadCatalog reads everything which isn't limited to a single namespace,
+// other than pg_namespace itself, which the caller already has as nss. Use
+// loadNamespace() to get the rest.
+func loadCatalog(ctx context.Context, tx Queryer, nss map[string]schemaNamespace) (*_OIDs, error) {
 	var (
 		m   = &_OIDs{}
 		err error
 	)
 
-	m.class, err = pgClass(tx, schema)
+	m.version, err = pgVersion(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.namespaces = map[objectID]string{}
+	for _, ns := range nss {
+		m.namespaces[ns.OID] = ns.NspName
+	}
+
+	m.classNames, err = pgClassName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.typ, err = pgType(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	enums, err := pgEnum(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	m.enum = map[objectID][]string{}
+	for _, e := range enums {
+		m.enum[e.EnumTypID] = append(m.enum[e.EnumTypID], e.EnumLabel)
+	}
+
+	m.rng, err = pgRange(ctx, tx, m.version)
+	if err != nil {
+		return nil, err
+	}
+
+	m.inherits, err = pgInherits(ctx, tx, m.version)
 	if err != nil {
 		return nil, err
 	}
 
-	m.typ, err = pgType(tx)
+	m.partitioned, err = pgPartitionedTable(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	m.inherits, err = pgInherits(tx)
+	atts, err := pgAttribute(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	m.attributes = map[objectID][]schemaAttribute{}
+	for _, a := range atts {
+		m.attributes[a.AttRelID] = append(m.attributes[a.AttRelID], a)
+	}
+
+	ads, err := pgAttrDef(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	m.attrdef = map[objectID]map[int]string{}
+	for _, ad := range ads {
+		defs, ok := m.attrdef[ad.AdRelID]
+		if !ok {
+			defs = map[int]string{}
+			m.attrdef[ad.AdRelID] = defs
+		}
+		defs[ad.AdNum] = ad.AdSrc
+	}
+
+	m.index, err = pgIndex(ctx, tx, m.version)
 	if err != nil {
 		return nil, err
 	}
 
-	m.attribute, err = pgAttribute(tx)
+	m.am, err = pgAm(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	m.index, err = pgIndex(tx)
+	m.opclass, err = pgOpClass(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	m.am, err = pgAm(tx)
+	m.collation, err = pgCollation(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	m.proc, err = pgProc(tx, schema)
+	m.language, err = pgLanguage(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	m.language, err = pgLanguage(tx)
+	m.eventTrigger, err = pgEventTrigger(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -358,11 +1405,119 @@ func loadSchema(tx *pgx.Tx, schema pgx.Oid) (*_OIDs, error) {
 	return m, nil
 }
 
+// loadNamespace gives a copy of the catalog, with the namespace specific
+// parts loaded. The shared parts are not copied.
+func (db *_OIDs) loadNamespace(ctx context.Context, tx Queryer, schema objectID) (*_OIDs, error) {
+	var (
+		m   = *db
+		err error
+	)
+
+	m.class, err = pgClass(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	m.proc, err = pgProc(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	m.constraint, err = pgConstraint(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	m.sequence, err = pgSequence(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	m.trigger, err = pgTrigger(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	m.policy, err = pgPolicy(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	descs, err := pgDescription(ctx, tx, schema)
+	if err != nil {
+		return nil, err
+	}
+	m.description = map[descriptionKey]string{}
+	for _, d := range descs {
+		m.description[descriptionKey{d.ClassOID, d.ObjOID, d.ObjSubID}] = d.Description
+	}
+
+	return &m, nil
+}
+
+// comment gives the COMMENT ON of an object. sub is the column number for
+// columns, and 0 for everything else.
+func (db *_OIDs) comment(catalog string, oid objectID, sub int) string {
+	return db.description[descriptionKey{catalog, oid, sub}]
+}
+
+// parent gives the partitioned table or index of a partition.
+func (db *_OIDs) parent(oid objectID) objectID {
+	for _, e := range db.inherits {
+		if e.InhRelID == oid {
+			return e.InhParent
+		}
+	}
+	return 0
+}
+
+// indexConstraint gives the name of the constraint implemented by an index
+func (db *_OIDs) indexConstraint(index objectID) string {
+	for _, c := range db.constraint {
+		if c.ConIndID == index && c.ConType != "f" {
+			return c.ConName
+		}
+	}
+	return ""
+}
+
+// attName gives the name of a column by its attnum.
+func (db *_OIDs) attName(rel objectID, num int) string {
+	for _, a := range db.attributes[rel] {
+		if a.AttNum == num {
+			return a.AttName
+		}
+	}
+	return ""
+}
+
+// relName gives the qualified name of a relation in any namespace.
+func (db *_OIDs) relName(oid objectID) QName {
+	c, ok := db.classNames[oid]
+	if !ok {
+		return QName{}
+	}
+	return QName{Schema: db.namespaces[c.RelNamespace], Name: c.RelName}
+}
+
+// typeQName gives the type with its namespace. For arrays it's the element
+// type.
+func (db *_OIDs) typeQName(oid objectID) QName {
+	t, ok := db.typ[oid]
+	if !ok {
+		return QName{}
+	}
+	if t.isArray() {
+		return db.typeQName(t.TypElem)
+	}
+	return QName{Schema: db.namespaces[t.TypNamespace], Name: t.TypName}
+}
+
 // give the name of a pg datatype. Returns 'float' for a simple type, or
 // 'float[]' for an array.
-func (db *_OIDs) typeName(oid pgx.Oid) string {
+func (db *_OIDs) typeName(oid objectID) string {
 	t := db.typ[oid]
-	if t.TypElem == 0 {
+	if !t.isArray() {
 		return t.TypName
 	}
 	et := db.typ[t.TypElem]
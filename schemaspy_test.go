@@ -0,0 +1,22 @@
+package schemaspy
+
+import (
+	"testing"
+)
+
+func TestTriggerWhen(t *testing.T) {
+	for def, want := range map[string]string{
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW EXECUTE FUNCTION s.f()":                                        "",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW WHEN ((new.name IS NOT NULL)) EXECUTE FUNCTION s.f()":          "(new.name IS NOT NULL)",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW WHEN ((new.name <> ')')) EXECUTE FUNCTION s.f()":               "(new.name <> ')')",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW WHEN ((new.name <> 'it''s (')) EXECUTE FUNCTION s.f()":         "(new.name <> 'it''s (')",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW WHEN ((new.\")\" > 0)) EXECUTE FUNCTION s.f()":                 "(new.\")\" > 0)",
+		"CREATE TRIGGER \" WHEN (\" BEFORE INSERT ON s.t FOR EACH ROW WHEN ((new.a > 0)) EXECUTE FUNCTION s.f()":           "(new.a > 0)",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW EXECUTE FUNCTION s.f(' WHEN (x)')":                             "",
+		"CREATE TRIGGER t BEFORE INSERT ON s.t FOR EACH ROW WHEN ((lower(new.a) = 'x')) EXECUTE FUNCTION s.f(' WHEN (y)')": "(lower(new.a) = 'x')",
+	} {
+		if have := triggerWhen(def); have != want {
+			t.Errorf("%s: have %#v, want %#v", def, have, want)
+		}
+	}
+}
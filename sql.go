@@ -0,0 +1,560 @@
+package schemaspy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// renderer makes SQL statements for single objects. Names are qualified with
+// schema, unless that's empty. Statements have no trailing ";".
+//
+// The Definition fields are used when they are set, since they come
+// straight from PostgreSQL. Otherwise the statement is made from the other
+// fields.
+type renderer struct {
+	schema string
+}
+
+func (r renderer) name(n string) string {
+	return QName{r.schema, n}.String()
+}
+
+func (r renderer) createTable(name string, t Relation) string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE " + r.name(name))
+	if t.PartitionOf.Name != "" {
+		fmt.Fprintf(&b, " PARTITION OF %s", t.PartitionOf)
+		if t.PartitionBound != "" {
+			b.WriteString(" " + t.PartitionBound)
+		}
+	} else {
+		b.WriteString(" (")
+		for i, c := range t.ColumnNames() {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n    " + columnDef(c, t.Columns[c]))
+		}
+		b.WriteString("\n)")
+		if len(t.Inherits) > 0 {
+			b.WriteString(" INHERITS (" + joinQNames(t.Inherits) + ")")
+		}
+	}
+	if t.PartitionStrategy != "" {
+		fmt.Fprintf(&b, " PARTITION BY %s (%s)", strings.ToUpper(t.PartitionStrategy), strings.Join(t.PartitionKey, ", "))
+	}
+	if len(t.Options) > 0 {
+		b.WriteString(" WITH (" + strings.Join(t.Options, ", ") + ")")
+	}
+	return b.String()
+}
+
+// columnDef is a column as used in CREATE TABLE and ADD COLUMN
+func columnDef(name string, c Column) string {
+	s := quoteIdent(name) + " " + c.FullType
+	if c.Generated != "" {
+		s += " GENERATED ALWAYS AS " + parens(c.Generated) + " STORED"
+	}
+	if c.Default != "" {
+		s += " DEFAULT " + c.Default
+	}
+	if c.NotNull && c.Identity == "" {
+		s += " NOT NULL"
+	}
+	if c.Identity != "" {
+		s += " GENERATED " + strings.ToUpper(c.Identity) + " AS IDENTITY"
+	}
+	return s
+}
+
+func (r renderer) createView(name string, t Relation) string {
+	var (
+		b    strings.Builder
+		opts []string
+	)
+	for _, o := range t.Options {
+		if !strings.HasPrefix(o, "check_option=") {
+			opts = append(opts, o)
+		}
+	}
+	fmt.Fprintf(&b, "CREATE %s %s", strings.ToUpper(t.Type), r.name(name))
+	if len(opts) > 0 {
+		b.WriteString(" WITH (" + strings.Join(opts, ", ") + ")")
+	}
+	b.WriteString(" AS\n" + strings.TrimSuffix(strings.TrimSpace(t.Definition), ";"))
+	if t.CheckOption != "" {
+		b.WriteString("\n  WITH " + strings.ToUpper(t.CheckOption) + " CHECK OPTION")
+	}
+	if t.Type == "materialized view" && !t.Populated {
+		b.WriteString("\n  WITH NO DATA")
+	}
+	return b.String()
+}
+
+func (r renderer) createIndex(name string, i Index) string {
+	if i.Definition != "" {
+		return i.Definition
+	}
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if i.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&b, "INDEX %s ON %s", quoteIdent(name), r.name(i.Table))
+	if i.Type != "" {
+		b.WriteString(" USING " + i.Type)
+	}
+	var keys []string
+	for _, k := range i.Keys {
+		keys = append(keys, indexKey(k))
+	}
+	if len(keys) == 0 {
+		keys = i.Columns
+	}
+	b.WriteString(" (" + strings.Join(keys, ", ") + ")")
+	if len(i.Include) > 0 {
+		b.WriteString(" INCLUDE (" + joinIdents(i.Include) + ")")
+	}
+	if i.NullsNotDistinct {
+		b.WriteString(" NULLS NOT DISTINCT")
+	}
+	if i.Predicate != "" {
+		b.WriteString(" WHERE " + parens(i.Predicate))
+	}
+	return b.String()
+}
+
+func indexKey(k IndexKey) string {
+	s := quoteIdent(k.Column)
+	if k.Column == "" {
+		s = parens(k.Expression)
+	}
+	if k.Collation != "" {
+		s += " COLLATE " + quoteIdent(k.Collation)
+	}
+	if k.OpClass != "" {
+		s += " " + k.OpClass
+	}
+	if k.Descending {
+		s += " DESC"
+		if !k.NullsFirst {
+			s += " NULLS LAST"
+		}
+	} else if k.NullsFirst {
+		s += " NULLS FIRST"
+	}
+	return s
+}
+
+func (r renderer) addConstraint(name string, c Constraint) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", r.name(c.Table), quoteIdent(name), constraintDef(c))
+}
+
+// constraintDef is the Definition, or what we can make of it. Check and
+// exclusion constraints need their Definition.
+func constraintDef(c Constraint) string {
+	if c.Definition != "" {
+		return c.Definition
+	}
+	var s string
+	switch c.Type {
+	case "primary key":
+		s = "PRIMARY KEY (" + joinIdents(c.Columns) + ")"
+	case "unique":
+		s = "UNIQUE (" + joinIdents(c.Columns) + ")"
+	case "foreign key":
+		s = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", joinIdents(c.Columns), c.RefTable, joinIdents(c.RefColumns))
+		if c.OnUpdate != "" && c.OnUpdate != "no action" {
+			s += " ON UPDATE " + strings.ToUpper(c.OnUpdate)
+		}
+		if c.OnDelete != "" && c.OnDelete != "no action" {
+			s += " ON DELETE " + strings.ToUpper(c.OnDelete)
+		}
+	}
+	if c.Deferrable {
+		s += " DEFERRABLE"
+		if c.InitiallyDeferred {
+			s += " INITIALLY DEFERRED"
+		}
+	}
+	if !c.Validated {
+		s += " NOT VALID"
+	}
+	return strings.TrimSpace(s)
+}
+
+func (r renderer) createSequence(name string, s Sequence) string {
+	var b strings.Builder
+	b.WriteString("CREATE SEQUENCE " + r.name(name))
+	if s.Type != "" {
+		b.WriteString(" AS " + s.Type)
+	}
+	b.WriteString(sequenceOptions(Sequence{}, s))
+	return b.String()
+}
+
+// sequenceOptions gives the options which differ between a and b, as used
+// by CREATE and ALTER SEQUENCE. Zero values are not set.
+func sequenceOptions(a, b Sequence) string {
+	var s string
+	for _, o := range []struct {
+		name string
+		a, b int
+	}{
+		{"INCREMENT BY", a.IncrementBy, b.IncrementBy},
+		{"MINVALUE", a.MinValue, b.MinValue},
+		{"MAXVALUE", a.MaxValue, b.MaxValue},
+		{"START WITH", a.Start, b.Start},
+		{"CACHE", a.Cache, b.Cache},
+	} {
+		if o.a != o.b && o.b != 0 {
+			s += " " + o.name + " " + strconv.Itoa(o.b)
+		}
+	}
+	if a.Cycle != b.Cycle {
+		if b.Cycle {
+			s += " CYCLE"
+		} else {
+			s += " NO CYCLE"
+		}
+	}
+	return s
+}
+
+func (r renderer) createType(name string, t Type) string {
+	switch t.Type {
+	case "enum":
+		var labels []string
+		for _, l := range t.Labels {
+			labels = append(labels, quoteLiteral(l))
+		}
+		return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", r.name(name), strings.Join(labels, ", "))
+	case "composite":
+		var attrs []string
+		for _, a := range (&Relation{Columns: t.Attributes}).ColumnNames() {
+			attrs = append(attrs, quoteIdent(a)+" "+t.Attributes[a].FullType)
+		}
+		return fmt.Sprintf("CREATE TYPE %s AS (%s)", r.name(name), strings.Join(attrs, ", "))
+	case "domain":
+		s := fmt.Sprintf("CREATE DOMAIN %s AS %s", r.name(name), t.BaseType)
+		if t.Default != "" {
+			s += " DEFAULT " + t.Default
+		}
+		if t.NotNull {
+			s += " NOT NULL"
+		}
+		for _, c := range sortedKeys(t.Constraints) {
+			s += fmt.Sprintf(" CONSTRAINT %s %s", quoteIdent(c), t.Constraints[c].Definition)
+		}
+		return s
+	case "range":
+		opts := []string{"subtype = " + t.Subtype}
+		for _, o := range []struct{ name, v string }{
+			{"collation", t.Collation},
+			{"canonical", t.Canonical},
+			{"subtype_diff", t.SubtypeDiff},
+			{"multirange_type_name", t.Multirange},
+		} {
+			if o.v != "" {
+				opts = append(opts, o.name+" = "+o.v)
+			}
+		}
+		return fmt.Sprintf("CREATE TYPE %s AS RANGE (%s)", r.name(name), strings.Join(opts, ", "))
+	default:
+		return ""
+	}
+}
+
+// typeKind is the keyword for DROP, ALTER, and COMMENT ON
+func typeKind(t Type) string {
+	if t.Type == "domain" {
+		return "DOMAIN"
+	}
+	return "TYPE"
+}
+
+func (r renderer) createFunction(f Function) string {
+	if f.Definition != "" {
+		return strings.TrimSpace(f.Definition)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE OR REPLACE %s %s(", functionKind(f), r.name(f.Name))
+	var args []string
+	for _, a := range f.Arguments {
+		if a.Mode == "table" {
+			continue
+		}
+		s := argType(a)
+		if a.Name != "" {
+			s = quoteIdent(a.Name) + " " + s
+		}
+		if a.Mode != "" && a.Mode != "in" {
+			s = strings.ToUpper(a.Mode) + " " + s
+		}
+		if a.Default != "" {
+			s += " DEFAULT " + a.Default
+		}
+		args = append(args, s)
+	}
+	b.WriteString(strings.Join(args, ", ") + ")")
+	if f.Kind != "procedure" {
+		result := f.Result
+		if result == "" {
+			result = f.ReturnType
+			if f.ReturnsSet {
+				result = "SETOF " + result
+			}
+		}
+		b.WriteString("\n RETURNS " + result)
+	}
+	b.WriteString("\n LANGUAGE " + f.Language)
+	var opts []string
+	if f.Volatility != "" && f.Volatility != "volatile" {
+		opts = append(opts, strings.ToUpper(f.Volatility))
+	}
+	if f.Strict {
+		opts = append(opts, "STRICT")
+	}
+	if f.SecurityDefiner {
+		opts = append(opts, "SECURITY DEFINER")
+	}
+	if f.Leakproof {
+		opts = append(opts, "LEAKPROOF")
+	}
+	if f.Parallel != "" && f.Parallel != "unsafe" {
+		opts = append(opts, "PARALLEL "+strings.ToUpper(f.Parallel))
+	}
+	if f.Cost != 0 && f.Cost != 100 {
+		opts = append(opts, "COST "+strconv.FormatFloat(f.Cost, 'g', -1, 64))
+	}
+	if f.ReturnsSet && f.Rows != 0 && f.Rows != 1000 {
+		opts = append(opts, "ROWS "+strconv.FormatFloat(f.Rows, 'g', -1, 64))
+	}
+	if len(opts) > 0 {
+		b.WriteString("\n " + strings.Join(opts, " "))
+	}
+	for _, c := range f.Config {
+		if i := strings.Index(c, "="); i > 0 {
+			fmt.Fprintf(&b, "\n SET %s TO %s", c[:i], c[i+1:])
+		}
+	}
+	tag := "$function$"
+	for n := 1; strings.Contains(f.Src, tag); n++ {
+		tag = fmt.Sprintf("$function%d$", n)
+	}
+	b.WriteString("\nAS " + tag + f.Src + tag)
+	return b.String()
+}
+
+// functionKind is "FUNCTION", "PROCEDURE", or "AGGREGATE"
+func functionKind(f Function) string {
+	switch f.Kind {
+	case "procedure", "aggregate":
+		return strings.ToUpper(f.Kind)
+	default:
+		return "FUNCTION"
+	}
+}
+
+// functionName is the name with the input argument types, as used by DROP
+// and COMMENT ON.
+func (r renderer) functionName(f Function) string {
+	var args []string
+	for _, a := range f.Arguments {
+		switch a.Mode {
+		case "", "in", "inout", "variadic":
+			args = append(args, argType(a))
+		}
+	}
+	if len(f.Arguments) == 0 {
+		args = f.ArgumentTypes
+	}
+	return r.name(f.Name) + "(" + strings.Join(args, ", ") + ")"
+}
+
+// argType is the type of an argument, qualified if it's not a built-in type.
+func argType(a Argument) string {
+	if a.TypeName.Schema == "" || a.TypeName.Schema == "pg_catalog" {
+		return a.Type
+	}
+	if a.Array {
+		return a.TypeName.String() + "[]"
+	}
+	return a.TypeName.String()
+}
+
+func (r renderer) createTrigger(table, name string, t Trigger) string {
+	if t.Definition != "" {
+		return t.Definition
+	}
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if t.Constraint {
+		b.WriteString("CONSTRAINT ")
+	}
+	fmt.Fprintf(&b, "TRIGGER %s %s ", quoteIdent(name), strings.ToUpper(t.Timing))
+	var events []string
+	for _, e := range t.Events {
+		e = strings.ToUpper(e)
+		if e == "UPDATE" && len(t.UpdateColumns) > 0 {
+			e += " OF " + joinIdents(t.UpdateColumns)
+		}
+		events = append(events, e)
+	}
+	b.WriteString(strings.Join(events, " OR ") + " ON " + r.name(table))
+	if t.OldTable != "" || t.NewTable != "" {
+		b.WriteString(" REFERENCING")
+		if t.OldTable != "" {
+			b.WriteString(" OLD TABLE AS " + quoteIdent(t.OldTable))
+		}
+		if t.NewTable != "" {
+			b.WriteString(" NEW TABLE AS " + quoteIdent(t.NewTable))
+		}
+	}
+	b.WriteString(" FOR EACH " + strings.ToUpper(t.Level))
+	if t.When != "" {
+		b.WriteString(" WHEN " + parens(t.When))
+	}
+	var args []string
+	for _, a := range t.Arguments {
+		args = append(args, quoteLiteral(a))
+	}
+	fmt.Fprintf(&b, " EXECUTE FUNCTION %s(%s)", t.Function, strings.Join(args, ", "))
+	return b.String()
+}
+
+// triggerEnable is the ALTER TABLE ... TRIGGER statement for the Enabled
+// state of a trigger.
+func (r renderer) triggerEnable(table, name string, t Trigger) string {
+	return fmt.Sprintf("ALTER TABLE %s %s TRIGGER %s", r.name(table), enabledClause(t.Enabled), quoteIdent(name))
+}
+
+// enabledClause is the ENABLE/DISABLE clause for an Enabled value of a
+// trigger or event trigger.
+func enabledClause(enabled string) string {
+	switch enabled {
+	case "always":
+		return "ENABLE ALWAYS"
+	case "replica":
+		return "ENABLE REPLICA"
+	case "disabled":
+		return "DISABLE"
+	default:
+		return "ENABLE"
+	}
+}
+
+func (r renderer) createPolicy(table, name string, p Policy) string {
+	s := fmt.Sprintf("CREATE POLICY %s ON %s", quoteIdent(name), r.name(table))
+	if !p.Permissive {
+		s += " AS RESTRICTIVE"
+	}
+	if p.Command != "" && p.Command != "all" {
+		s += " FOR " + strings.ToUpper(p.Command)
+	}
+	if len(p.Roles) > 0 {
+		var roles []string
+		for _, r := range p.Roles {
+			if r == "public" {
+				r = "PUBLIC"
+			} else {
+				r = quoteIdent(r)
+			}
+			roles = append(roles, r)
+		}
+		s += " TO " + strings.Join(roles, ", ")
+	}
+	if p.Using != "" {
+		s += " USING " + parens(p.Using)
+	}
+	if p.WithCheck != "" {
+		s += " WITH CHECK " + parens(p.WithCheck)
+	}
+	return s
+}
+
+func createEventTrigger(name string, e EventTrigger) string {
+	s := fmt.Sprintf("CREATE EVENT TRIGGER %s ON %s", quoteIdent(name), e.Event)
+	if len(e.Tags) > 0 {
+		var tags []string
+		for _, t := range e.Tags {
+			tags = append(tags, quoteLiteral(t))
+		}
+		s += " WHEN TAG IN (" + strings.Join(tags, ", ") + ")"
+	}
+	return s + " EXECUTE FUNCTION " + e.Function + "()"
+}
+
+// commentOn makes a COMMENT ON statement, such as
+// `COMMENT ON TABLE public.users IS 'all users'`. An empty comment removes
+// the comment.
+func commentOn(kind, target, comment string) string {
+	c := "NULL"
+	if comment != "" {
+		c = quoteLiteral(comment)
+	}
+	return fmt.Sprintf("COMMENT ON %s %s IS %s", kind, target, c)
+}
+
+// relationKind is the keyword for DROP, ALTER, and COMMENT ON
+func relationKind(t Relation) string {
+	switch t.Type {
+	case "view", "materialized view":
+		return strings.ToUpper(t.Type)
+	default:
+		return "TABLE"
+	}
+}
+
+func isView(t Relation) bool {
+	return t.Type == "view" || t.Type == "materialized view"
+}
+
+// quoteLiteral makes a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// parens wraps an expression in parentheses, unless it already is.
+func parens(s string) string {
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		depth := 0
+		for i, c := range s {
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 {
+				if i == len(s)-1 {
+					return s
+				}
+				break
+			}
+		}
+	}
+	return "(" + s + ")"
+}
+
+func joinIdents(names []string) string {
+	var qs []string
+	for _, n := range names {
+		qs = append(qs, quoteIdent(n))
+	}
+	return strings.Join(qs, ", ")
+}
+
+func joinQNames(names []QName) string {
+	var qs []string
+	for _, n := range names {
+		qs = append(qs, n.String())
+	}
+	return strings.Join(qs, ", ")
+}
+
+// sortedKeys gives the keys of a map[string]..., ordered alphabetically.
+func sortedKeys(m interface{}) []string {
+	return keys(m, m)
+}
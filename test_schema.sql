@@ -1,4 +1,5 @@
 DROP SCHEMA IF EXISTS schemaspyint CASCADE;
+DROP SCHEMA IF EXISTS schemaspyint_other CASCADE;
 CREATE SCHEMA schemaspyint;
 SET search_path TO schemaspyint;
 
@@ -12,6 +13,20 @@ CREATE TABLE root
   ( id text NOT NULL
   );
 CREATE TABLE root_123 () INHERITS (root);
+CREATE TABLE stamped
+  ( at timestamptz
+  );
+CREATE TABLE root_456 () INHERITS (stamped, root);
+
+CREATE TABLE measurement
+  ( logdate date NOT NULL
+  , city text
+  ) PARTITION BY RANGE (logdate);
+CREATE TABLE measurement_2020 PARTITION OF measurement FOR VALUES FROM ('2020-01-01') TO ('2021-01-01');
+CREATE TABLE measurement_2021 PARTITION OF measurement FOR VALUES FROM ('2021-01-01') TO ('2022-01-01') PARTITION BY LIST (city);
+CREATE TABLE measurement_2021_ams PARTITION OF measurement_2021 FOR VALUES IN ('Amsterdam');
+CREATE TABLE measurement_rest PARTITION OF measurement DEFAULT;
+CREATE INDEX measurement_logdate ON measurement (logdate);
 
 CREATE TABLE indexed
   ( major int
@@ -21,11 +36,72 @@ CREATE TABLE indexed
 CREATE INDEX index_indexed ON indexed (major, minor);
 CREATE UNIQUE INDEX unique_indexed ON indexed (name);
 CREATE INDEX indexed_name_lower_idx ON indexed (lower(name), minor);
+CREATE INDEX indexed_fancy ON indexed (major DESC NULLS LAST, name varchar_pattern_ops) INCLUDE (minor) WHERE major > 0;
+CREATE INDEX indexed_collated ON indexed (name COLLATE "C");
+CLUSTER indexed USING index_indexed;
+ALTER TABLE simple REPLICA IDENTITY USING INDEX simple_pkey;
+
+-- this fails on the duplicate, and leaves an invalid index behind
+CREATE TABLE broken
+  ( v int
+  );
+INSERT INTO broken VALUES (1), (1);
+CREATE UNIQUE INDEX CONCURRENTLY broken_v ON broken (v);
+
+CREATE TABLE constrained
+  ( id int PRIMARY KEY
+  , simple_id uuid NOT NULL REFERENCES simple (id) ON DELETE CASCADE
+  , amount int CONSTRAINT positive_amount CHECK (amount > 0)
+  , code text
+  , CONSTRAINT unique_code UNIQUE (code) DEFERRABLE INITIALLY DEFERRED
+  );
+
+-- constraint names are per table
+CREATE TABLE refunds
+  ( amount int CONSTRAINT positive_amount CHECK (amount > 0)
+  );
+
+CREATE TABLE defaulted
+  ( id int GENERATED ALWAYS AS IDENTITY
+  , serial_id serial
+  , created timestamptz NOT NULL DEFAULT now()
+  , amount int
+  , doubled int GENERATED ALWAYS AS (amount * 2) STORED
+  );
+
+CREATE TABLE typed
+  ( code varchar(255)
+  , price numeric(10,2)
+  , at timestamp(3)
+  , tags text[]
+  , spot point
+  , label name
+  );
+
+CREATE TABLE dropped
+  ( a int
+  , b int
+  , c int
+  );
+ALTER TABLE dropped DROP COLUMN b;
+CREATE INDEX dropped_c ON dropped (c);
 
 CREATE VIEW myview_now AS SELECT id, name FROM simple where t > current_timestamp;
 CREATE MATERIALIZED VIEW myview_forever AS SELECT id, name FROM simple where t > current_timestamp;
+CREATE VIEW myview_checked WITH (security_barrier) AS SELECT id, name FROM simple WHERE name <> '' WITH LOCAL CHECK OPTION;
+CREATE MATERIALIZED VIEW myview_later AS SELECT id, name FROM simple WITH NO DATA;
+CREATE UNIQUE INDEX myview_later_id ON myview_later (id);
+CREATE UNIQUE INDEX myview_later_name ON myview_later (name) WHERE name <> '';
 
 CREATE SEQUENCE countme INCREMENT BY 42 MINVALUE 4001 MAXVALUE 400100 START 40010 CYCLE;
+CREATE SEQUENCE "Order" AS smallint CACHE 10 OWNED BY constrained.code;
+SELECT setval('"Order"', 12);
+
+CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy');
+ALTER TYPE mood ADD VALUE 'meh' BEFORE 'ok';
+CREATE TYPE address AS (street text, number int);
+CREATE DOMAIN posint AS int NOT NULL DEFAULT 1 CONSTRAINT posint_check CHECK (VALUE > 0);
+CREATE TYPE floatrange AS RANGE (subtype = float8, subtype_diff = float8mi);
 
 CREATE FUNCTION my_first_sql_function() RETURNS varchar AS $$
     SELECT name FROM schemaspyint.indexed
@@ -41,3 +117,74 @@ $$ LANGUAGE plpgsql;
 CREATE FUNCTION my_first_variadic_function(VARIADIC arr numeric[]) RETURNS numeric AS $$
     SELECT min($1[i]) FROM generate_subscripts($1, 1) g(i);
 $$ LANGUAGE SQL;
+
+CREATE FUNCTION overloaded(a int) RETURNS int AS $$
+    SELECT a;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION overloaded(a text) RETURNS text AS $$
+    SELECT a;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION detailed(a int, INOUT b text DEFAULT 'hi', OUT c int)
+  STABLE STRICT SECURITY DEFINER PARALLEL SAFE COST 5
+  SET search_path = schemaspyint
+  AS $$
+    SELECT b, a * 2;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION quoted(a int, "b DEFAULT c" text DEFAULT 'x DEFAULT y, z', d int DEFAULT 2) RETURNS text AS $$
+    SELECT $2;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION settable() RETURNS TABLE (id int, name text) AS $$
+    SELECT 1, 'one';
+$$ LANGUAGE SQL;
+
+CREATE PROCEDURE proc(a int) AS $$
+    SELECT a;
+$$ LANGUAGE SQL;
+
+CREATE FUNCTION audit() RETURNS trigger AS $$
+BEGIN
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER simple_audit BEFORE INSERT OR UPDATE OF name ON simple
+  FOR EACH ROW WHEN (NEW.name IS NOT NULL) EXECUTE FUNCTION audit('a', 'b');
+CREATE TRIGGER simple_log AFTER DELETE ON simple
+  REFERENCING OLD TABLE AS gone FOR EACH STATEMENT EXECUTE FUNCTION audit();
+ALTER TABLE simple DISABLE TRIGGER simple_log;
+CREATE TRIGGER refunds_audit BEFORE INSERT ON refunds
+  FOR EACH ROW WHEN (NEW.amount::text <> ')') EXECUTE FUNCTION audit();
+
+CREATE TABLE tenanted
+  ( tenant text NOT NULL
+  , data text
+  );
+ALTER TABLE tenanted ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenanted FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenanted
+  USING (tenant = current_user)
+  WITH CHECK (tenant = current_user);
+CREATE POLICY no_deletes ON tenanted AS RESTRICTIVE FOR DELETE TO public
+  USING (false);
+
+GRANT SELECT ON simple TO PUBLIC;
+GRANT UPDATE (name) ON indexed TO PUBLIC;
+REVOKE EXECUTE ON FUNCTION audit() FROM PUBLIC;
+
+COMMENT ON SCHEMA schemaspyint IS 'integration tests';
+COMMENT ON TABLE tenanted IS 'one row per tenant';
+COMMENT ON COLUMN tenanted.tenant IS 'the owning role';
+COMMENT ON INDEX indexed_collated IS 'sorts bytewise';
+COMMENT ON PROCEDURE proc(int) IS 'does nothing';
+
+CREATE SCHEMA schemaspyint_other;
+SET search_path TO schemaspyint_other;
+
+CREATE TABLE elsewhere
+  ( id int
+  , simple_id uuid REFERENCES schemaspyint.simple (id)
+  , feeling schemaspyint.mood
+  ) INHERITS (schemaspyint.root);
@@ -0,0 +1,116 @@
+package schemaspy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned by the Describe functions when the deadline of
+// the context is exceeded, or when PostgreSQL cancelled a query because of
+// statement_timeout or lock_timeout. A cancelled context is not a timeout.
+type TimeoutError struct {
+	// Err is either the context error, or the error from the driver
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return "schemaspy: timeout: " + e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeouts for SetTimeouts(). Zero values are not set.
+type Timeouts struct {
+	// Statement is used for statement_timeout
+	Statement time.Duration
+	// Lock is used for lock_timeout
+	Lock time.Duration
+}
+
+// SetTimeouts sets statement_timeout and lock_timeout for the rest of the
+// transaction, with SET LOCAL. Use it before Describe() if the transaction
+// is only used to describe the schema.
+func SetTimeouts(ctx context.Context, tx Queryer, t Timeouts) error {
+	if t.Statement > 0 {
+		if err := exec(ctx, tx, fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds(t.Statement))); err != nil {
+			return err
+		}
+	}
+	if t.Lock > 0 {
+		if err := exec(ctx, tx, fmt.Sprintf("SET LOCAL lock_timeout = %d", milliseconds(t.Lock))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDeadline uses the deadline of the context, if any, for both
+// statement_timeout and lock_timeout. See SetTimeouts().
+func SetDeadline(ctx context.Context, tx Queryer) error {
+	if err := ctx.Err(); err != nil {
+		return asTimeout(ctx, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	left := time.Until(deadline)
+	return asTimeout(ctx, SetTimeouts(ctx, tx, Timeouts{Statement: left, Lock: left}))
+}
+
+// exec runs a statement which returns no rows
+func exec(ctx context.Context, tx Queryer, sql string) error {
+	rows, err := tx.Query(ctx, sql)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// rounded up, since 0 disables the timeout
+func milliseconds(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// PostgreSQL error codes
+const (
+	pgQueryCanceled    = "57014" // statement_timeout
+	pgLockNotAvailable = "55P03" // lock_timeout
+)
+
+// asTimeout wraps err in a *TimeoutError if it was caused by a timeout. When
+// the context was cancelled err is returned unchanged, even though the
+// driver may have cancelled the query with pgQueryCanceled.
+func asTimeout(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch cerr := ctx.Err(); {
+	case errors.Is(cerr, context.Canceled):
+		return err
+	case cerr != nil:
+		return &TimeoutError{Err: cerr}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &TimeoutError{Err: err}
+	}
+	var pgErr sqlStater
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case pgQueryCanceled, pgLockNotAvailable:
+			return &TimeoutError{Err: err}
+		}
+	}
+	return err
+}
+
+// sqlStater is implemented by the errors of pgx and lib/pq
+type sqlStater interface {
+	SQLState() string
+}
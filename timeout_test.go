@@ -0,0 +1,73 @@
+package schemaspy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// like pgconn.PgError and pq.Error
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pg error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestAsTimeout(t *testing.T) {
+	bg := context.Background()
+	if have := asTimeout(bg, nil); have != nil {
+		t.Errorf("have %#v, want nil", have)
+	}
+
+	other := errors.New("other")
+	if have, want := asTimeout(bg, other), other; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	for _, code := range []string{pgQueryCanceled, pgLockNotAvailable} {
+		var te *TimeoutError
+		if have, want := errors.As(asTimeout(bg, fmt.Errorf("wrapped: %w", sqlStateError(code))), &te), true; have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+
+	var te *TimeoutError
+	if have, want := errors.As(asTimeout(bg, fmt.Errorf("wrapped: %w", context.DeadlineExceeded)), &te), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := asTimeout(bg, context.Canceled), context.Canceled; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// a cancelled query is not a timeout when the context was cancelled
+	ctx, cancel := context.WithCancel(bg)
+	cancel()
+	canceled := sqlStateError(pgQueryCanceled)
+	if have, want := asTimeout(ctx, canceled), error(canceled); have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	ctx, cancel = context.WithDeadline(bg, time.Now())
+	defer cancel()
+	err := asTimeout(ctx, other)
+	if have, want := errors.As(err, &te), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+	if have, want := errors.Is(err, context.DeadlineExceeded), true; have != want {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestMilliseconds(t *testing.T) {
+	for d, want := range map[time.Duration]int64{
+		time.Second:             1000,
+		time.Millisecond:        1,
+		time.Microsecond:        1,
+		1500 * time.Microsecond: 2,
+	} {
+		if have := milliseconds(d); have != want {
+			t.Errorf("have %#v, want %#v", have, want)
+		}
+	}
+}